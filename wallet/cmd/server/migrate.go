@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/workshops/wallet/internal/config"
+	"github.com/workshops/wallet/internal/migrate"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/repository/mongo"
+	"github.com/workshops/wallet/internal/repository/postgre"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"github.com/workshops/wallet/migrations"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+const migrateUsage = "usage: migrate [flags] postgres|mongo up|down|status [version]"
+
+// migrators returns the migration drivers of both databases, by name.
+func migrators(db *sql.DB, dbm *mongodriver.Client) (map[string]migrate.Driver, error) {
+	pg, err := postgre.NewMigrator(db, migrations.Postgres)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]migrate.Driver{"postgres": pg, "mongo": mongo.NewMigrator(dbm)}, nil
+}
+
+// admin bootstraps the configured admin on a database, named as its migration driver.
+type admin func(ctx context.Context, database string) error
+
+// newAdmin bootstraps the admin of cfg on both databases.
+func newAdmin(cfg *config.Application, db *sql.DB, dbm *mongodriver.Client) admin {
+	repos := map[string]wallet.Repository{"postgres": postgre.NewRepository(db), "mongo": mongo.NewRepository(dbm)}
+
+	return func(ctx context.Context, database string) error {
+		return bootstrapAdmin(ctx, cfg.Admin, repos[database], timeouts(cfg.Timeouts))
+	}
+}
+
+// bootstrapAdmin makes the configured admin on the repository. It is the last step of migrating
+// a database to its latest version, as it needs the roles the migrations add. Leaving the name
+// empty skips it.
+func bootstrapAdmin(ctx context.Context, cfg *config.Admin, repo wallet.Repository, t wallet.Timeouts) error {
+	if cfg.Name == "" {
+		return nil
+	}
+
+	service := wallet.NewService(repo, nil, nil, wallet.QueueConfig{}, t)
+	defer service.Close()
+
+	err := service.BootstrapAdmin(ctx, models.Credentials{Name: cfg.Name, Password: cfg.Password})
+	if err != nil {
+		return fmt.Errorf("bootstrap admin %q: %w", cfg.Name, err)
+	}
+
+	return nil
+}
+
+// migrateAll brings both databases to their latest version and bootstraps the admin on them.
+func migrateAll(ctx context.Context, drivers map[string]migrate.Driver, bootstrap admin) error {
+	for _, name := range []string{"postgres", "mongo"} {
+		err := migrate.To(ctx, drivers[name], migrate.Latest)
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", name, err)
+		}
+
+		err = bootstrap(ctx, name)
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateCommand runs the migrate subcommand. Up goes to the given version or the latest one,
+// where it bootstraps the admin, down to the given version or the one before the newest applied,
+// status lists the migrations.
+func migrateCommand(ctx context.Context, drivers map[string]migrate.Driver, bootstrap admin, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	driver, ok := drivers[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown database %q, %s", args[0], migrateUsage)
+	}
+
+	target := migrate.Latest
+
+	if len(args) == 3 {
+		version, err := strconv.Atoi(args[2])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[2])
+		}
+
+		target = version
+	}
+
+	switch args[1] {
+	case "up":
+		err := migrate.To(ctx, driver, target)
+		if err != nil || target != migrate.Latest {
+			return err
+		}
+
+		return bootstrap(ctx, args[0])
+	case "down":
+		if target == migrate.Latest {
+			previous, err := migrate.Previous(ctx, driver)
+			if err != nil {
+				return err
+			}
+
+			target = previous
+		}
+
+		return migrate.To(ctx, driver, target)
+	case "status":
+		states, err := migrate.Status(ctx, driver)
+		if err != nil {
+			return err
+		}
+
+		for _, state := range states {
+			status := "pending"
+			if state.Applied {
+				status = "applied"
+			}
+
+			log.Printf("%06d_%s %s", state.Version, state.Name, status)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q, %s", args[1], migrateUsage)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/config"
+	"github.com/workshops/wallet/internal/migrate"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// fakeDriver keeps the applied versions of three migrations in memory.
+type fakeDriver struct {
+	applied map[int]bool
+}
+
+func (d *fakeDriver) Migrations() []migrate.Migration {
+	return []migrate.Migration{{Version: 1, Name: "one"}, {Version: 2, Name: "two"}, {Version: 3, Name: "roles"}}
+}
+
+func (d *fakeDriver) Lock(context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+func (d *fakeDriver) Applied(context.Context) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	for version := range d.applied {
+		applied[version] = true
+	}
+
+	return applied, nil
+}
+
+func (d *fakeDriver) Up(_ context.Context, m migrate.Migration) error {
+	d.applied[m.Version] = true
+	return nil
+}
+
+func (d *fakeDriver) Down(_ context.Context, m migrate.Migration) error {
+	delete(d.applied, m.Version)
+	return nil
+}
+
+//nolint
+func TestMigrateCommandBootstrapsAdmin(t *testing.T) {
+	driver := &fakeDriver{applied: make(map[int]bool)}
+	drivers := map[string]migrate.Driver{"postgres": driver}
+
+	var bootstrapped []string
+	bootstrap := func(_ context.Context, database string) error {
+		bootstrapped = append(bootstrapped, database)
+		return nil
+	}
+
+	// The roles are missing below the latest version, so is the admin.
+	require.NoError(t, migrateCommand(context.Background(), drivers, bootstrap, []string{"postgres", "up", "2"}))
+	assert.Empty(t, bootstrapped)
+
+	require.NoError(t, migrateCommand(context.Background(), drivers, bootstrap, []string{"postgres", "up"}))
+	assert.Equal(t, []string{"postgres"}, bootstrapped)
+
+	require.NoError(t, migrateCommand(context.Background(), drivers, bootstrap, []string{"postgres", "down"}))
+	assert.Equal(t, []string{"postgres"}, bootstrapped)
+}
+
+//nolint
+func TestBootstrapAdmin(t *testing.T) {
+	repo := memory.NewRepository()
+	admin := &config.Admin{Name: "root", Password: "password123"}
+
+	require.NoError(t, bootstrapAdmin(context.Background(), &config.Admin{}, repo, wallet.Timeouts{}))
+	_, err := repo.GetUserByName(context.Background(), "root")
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+
+	require.NoError(t, bootstrapAdmin(context.Background(), admin, repo, wallet.Timeouts{}))
+	// Migrating again leaves the admin as it is.
+	require.NoError(t, bootstrapAdmin(context.Background(), admin, repo, wallet.Timeouts{}))
+
+	user, err := repo.GetUserByName(context.Background(), "root")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/workshops/wallet/internal/services/wallet"
+	"google.golang.org/grpc"
+)
+
+// server is an API the binary runs until it is shut down.
+type server interface {
+	name() string
+	// serve blocks until the server fails or is shut down, in which case it returns nil.
+	serve() error
+	// shutdown stops accepting connections and waits for the calls in flight until ctx is done.
+	shutdown(ctx context.Context) error
+}
+
+type httpServer struct {
+	srv *nethttp.Server
+}
+
+func (s *httpServer) name() string {
+	return "HTTP"
+}
+
+func (s *httpServer) serve() error {
+	log.Printf("start HTTP server at %s", s.srv.Addr)
+
+	err := s.srv.ListenAndServe()
+	if errors.Is(err, nethttp.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *httpServer) shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		s.srv.Close()
+	}
+
+	return err
+}
+
+type grpcServer struct {
+	srv  *grpc.Server
+	addr string
+}
+
+func (s *grpcServer) name() string {
+	return "gRPC"
+}
+
+func (s *grpcServer) serve() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("start gRPC server at %s", listener.Addr().String())
+
+	err = s.srv.Serve(listener)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *grpcServer) shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// closer releases a resource once the servers are down.
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// closeServices drains the transaction queues of the services.
+func closeServices(services ...*wallet.Service) func(context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+
+		go func() {
+			for _, service := range services {
+				service.Close()
+			}
+
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// serveUntil runs the servers until ctx is done or one of them fails. Then it shuts the servers down
+// and runs the closers in order, all within timeout. It returns the first error met on the way.
+func serveUntil(ctx context.Context, servers []server, closers []closer, timeout time.Duration) error {
+	failed := make(chan error, len(servers))
+
+	for _, s := range servers {
+		s := s
+
+		go func() {
+			err := s.serve()
+			if err != nil {
+				log.Printf("%s server failed: %v", s.name(), err)
+			}
+
+			failed <- err
+		}()
+	}
+
+	var first error
+
+	select {
+	case <-ctx.Done():
+		log.Print("shutting down")
+	case first = <-failed:
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	record := func(step string, err error) {
+		if err == nil {
+			return
+		}
+
+		log.Printf("%s: %v", step, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if first == nil {
+			first = err
+		}
+	}
+
+	for _, s := range servers {
+		s := s
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			record("shut down "+s.name()+" server", s.shutdown(sctx))
+		}()
+	}
+
+	wg.Wait()
+
+	for _, c := range closers {
+		record("close "+c.name, c.close(sctx))
+	}
+
+	return first
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	nethttp "net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint
+func TestServeUntilDrains(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := listener.Addr().String()
+	listener.Close()
+
+	started := make(chan struct{})
+	handler := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	steps := make([]string, 0)
+	closers := []closer{
+		{name: "first", close: func(context.Context) error { steps = append(steps, "first"); return nil }},
+		{name: "second", close: func(context.Context) error { steps = append(steps, "second"); return nil }},
+	}
+
+	result := make(chan error)
+
+	go func() {
+		result <- serveUntil(ctx, []server{&httpServer{srv: &nethttp.Server{Addr: addr, Handler: handler}}}, closers, time.Second)
+	}()
+
+	body := make(chan string)
+
+	go func() {
+		for {
+			resp, err := nethttp.Get("http://" + addr)
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body <- string(b)
+
+			return
+		}
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, "done", <-body)
+	assert.NoError(t, <-result)
+	assert.Equal(t, []string{"first", "second"}, steps)
+}
+
+//nolint
+func TestServeUntilDeadline(t *testing.T) {
+	closers := []closer{
+		{name: "queues", close: closeServices()},
+		{name: "stuck", close: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := serveUntil(ctx, nil, closers, 10*time.Millisecond)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -1,9 +1,147 @@
 package config
 
+import (
+	"strings"
+	"time"
+)
+
+// APIs the server can run.
+const (
+	APIHTTP = "http"
+	APIGRPC = "grpc"
+)
+
+// Application is the whole configuration of the server. Nested sections prefix the env
+// variables of their fields with their own env tag, e.g. POSTGRES_DSN.
 type Application struct {
-	DB *Database
+	// Serve lists the APIs to run, separated by commas.
+	Serve string `yaml:"serve" env:"SERVE"`
+	// Migrate applies the pending schema migrations of both databases at startup.
+	Migrate  bool      `yaml:"migrate" env:"MIGRATE"`
+	HTTP     *Listener `yaml:"http" env:"HTTP"`
+	GRPC     *Listener `yaml:"grpc" env:"GRPC"`
+	Postgres *Database `yaml:"postgres" env:"POSTGRES"`
+	Mongo    *Database `yaml:"mongo" env:"MONGO"`
+	JWT      *JWT      `yaml:"jwt"`
+	Admin    *Admin    `yaml:"admin" env:"ADMIN"`
+	Fee      *Fee      `yaml:"fee"`
+	Rates    *Rates    `yaml:"rates"`
+	Queue    *Queue    `yaml:"queue"`
+	// Timeouts bound the database calls made for a request and the shutdown.
+	Timeouts *Timeouts `yaml:"timeouts"`
+}
+
+// Serves reports whether api is one of the APIs to run.
+func (a *Application) Serves(api string) bool {
+	for _, name := range strings.Split(a.Serve, ",") {
+		if strings.TrimSpace(name) == api {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Listener is the address a server accepts connections on.
+type Listener struct {
+	Addr string `yaml:"addr" env:"ADDR"`
 }
 
 type Database struct {
-	DSN string `env:"DSN"`
+	DSN string `yaml:"dsn" env:"DSN" secret:"true"`
+}
+
+// JWT configures the tokens users authenticate with. Access tokens are short-lived and
+// renewed with the refresh token issued with them.
+type JWT struct {
+	// Secret signs HS256 tokens. With a signing key it only verifies, until SecretUntil,
+	// which lets the tokens issued before the switch run out.
+	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	// SecretUntil is the RFC 3339 time HS256 tokens stop being accepted once a signing key is set.
+	SecretUntil string `yaml:"secret_until" env:"JWT_SECRET_UNTIL"`
+	// SigningKey is the PEM file of the RSA or Ed25519 private key tokens are signed with.
+	SigningKey string `yaml:"signing_key" env:"JWT_SIGNING_KEY"`
+	// KeyID is the kid of the signing key.
+	KeyID string `yaml:"key_id" env:"JWT_KEY_ID"`
+	// VerificationKeys lists the retired keys tokens are still accepted with, as kid=path
+	// pairs separated by commas.
+	VerificationKeys string        `yaml:"verification_keys" env:"JWT_VERIFICATION_KEYS"`
+	AccessTTL        time.Duration `yaml:"access_ttl" env:"JWT_ACCESS_TTL"`
+	RefreshTTL       time.Duration `yaml:"refresh_ttl" env:"JWT_REFRESH_TTL"`
+}
+
+// MinSecretLength is the shortest HS256 secret accepted, as many bytes as the hash it keys.
+const MinSecretLength = 32
+
+// SecretExpiry parses SecretUntil.
+func (j *JWT) SecretExpiry() (time.Time, error) {
+	return time.Parse(time.RFC3339, j.SecretUntil)
+}
+
+// KeyFile is a key to verify tokens with.
+type KeyFile struct {
+	ID   string
+	Path string
+}
+
+// VerificationKeyFiles splits VerificationKeys. An entry without a kid or a path is kept
+// with the part missing, for Validate to report.
+func (j *JWT) VerificationKeyFiles() []KeyFile {
+	files := make([]KeyFile, 0)
+
+	for _, entry := range strings.Split(j.VerificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		file := KeyFile{ID: strings.TrimSpace(parts[0])}
+
+		if len(parts) == 2 {
+			file.Path = strings.TrimSpace(parts[1])
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
+
+// Admin is the account made an admin when a database is migrated to its latest version, and on
+// the memory backend at startup, so the first admin needs no access to the databases. Leaving
+// the name empty skips it.
+type Admin struct {
+	Name     string `yaml:"name" env:"NAME"`
+	Password string `yaml:"password" env:"PASSWORD" secret:"true"`
+}
+
+// Fee configures the commission charged on every transfer.
+type Fee struct {
+	Percent  float64 `yaml:"percent" env:"FEE_PERCENT"`
+	Min      int     `yaml:"min" env:"FEE_MIN"`
+	Max      int     `yaml:"max" env:"FEE_MAX"`
+	Rounding string  `yaml:"rounding" env:"FEE_ROUNDING"`
+	WalletID string  `yaml:"wallet_id" env:"FEE_WALLET_ID"`
+}
+
+// Rates configures where exchange rates are loaded from.
+type Rates struct {
+	File string `yaml:"file" env:"RATES_FILE"`
+}
+
+// Queue configures the priority queue transactions are processed through.
+type Queue struct {
+	Workers  int           `yaml:"workers" env:"QUEUE_WORKERS"`
+	Capacity int           `yaml:"capacity" env:"QUEUE_CAPACITY"`
+	MaxWait  time.Duration `yaml:"max_wait" env:"QUEUE_MAX_WAIT"`
+}
+
+// Timeouts configures how long the database calls of each kind of operation may take,
+// and how long the server may take to drain and close everything once it is stopped.
+type Timeouts struct {
+	Read        time.Duration `yaml:"read" env:"TIMEOUT_READ"`
+	Write       time.Duration `yaml:"write" env:"TIMEOUT_WRITE"`
+	Transaction time.Duration `yaml:"transaction" env:"TIMEOUT_TRANSACTION"`
+	Shutdown    time.Duration `yaml:"shutdown" env:"TIMEOUT_SHUTDOWN"`
 }
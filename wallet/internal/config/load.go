@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the env variable that points to the YAML file when the -config flag is not given.
+const FileEnv = "CONFIG_FILE"
+
+// Load overrides the defaults in cfg with, in increasing order of precedence, the YAML file,
+// the env variables and the command line flags, then validates the result. It returns the
+// arguments left after the flags. Every setting has a flag named after its YAML path, e.g. -queue.max-wait.
+func Load(cfg *Application, args []string) ([]string, error) {
+	allocate(reflect.ValueOf(cfg).Elem())
+	settings := fields(reflect.ValueOf(cfg).Elem(), "", "")
+
+	fs := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv(FileEnv), "path of the YAML config file")
+
+	// Flags are applied last but parsed first, as they may name the file.
+	overrides := make([]func(), 0)
+
+	for _, s := range settings {
+		s := s
+
+		usage := "sets " + s.path
+		if s.env != "" {
+			usage += ", overrides $" + s.env
+		}
+
+		fs.Func(s.flag(), usage, func(raw string) error {
+			value := reflect.New(s.value.Type()).Elem()
+
+			err := set(value, raw)
+			if err != nil {
+				return err
+			}
+
+			overrides = append(overrides, func() { s.value.Set(value) })
+
+			return nil
+		})
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		err = loadFile(cfg, *file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		raw, ok := os.LookupEnv(s.env)
+		if s.env == "" || !ok {
+			continue
+		}
+
+		err = set(s.value, raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "env %s", s.env)
+		}
+	}
+
+	for _, override := range overrides {
+		override()
+	}
+
+	return fs.Args(), cfg.Validate()
+}
+
+func loadFile(cfg *Application, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "config file")
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrapf(err, "config file %s", path)
+	}
+
+	return nil
+}
+
+// String lists every setting with its YAML path, one per line. Secrets are masked, so it is safe to log.
+func (a *Application) String() string {
+	var b strings.Builder
+
+	for _, s := range fields(reflect.ValueOf(a).Elem(), "", "") {
+		fmt.Fprintf(&b, "%s: %s\n", s.path, s.display())
+	}
+
+	return b.String()
+}
+
+// setting is a leaf field of the configuration.
+type setting struct {
+	value  reflect.Value
+	path   string
+	env    string
+	secret bool
+}
+
+func (s setting) flag() string {
+	return strings.ReplaceAll(s.path, "_", "-")
+}
+
+// display formats the value for logs. Only the password of a secret URL is shown masked,
+// any other secret is hidden completely.
+func (s setting) display() string {
+	raw := fmt.Sprint(s.value.Interface())
+	if !s.secret || raw == "" {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err == nil && u.User != nil {
+		return u.Redacted()
+	}
+
+	return "********"
+}
+
+// allocate creates the missing sections, so every setting has somewhere to be loaded into.
+func allocate(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Ptr {
+			continue
+		}
+
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+
+		allocate(f.Elem())
+	}
+}
+
+// fields walks the sections of v and returns their settings. Missing sections are skipped.
+func fields(v reflect.Value, path, env string) []setting {
+	settings := make([]setting, 0)
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := path + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		prefix := env + field.Tag.Get("env")
+
+		if field.Type.Kind() == reflect.Ptr {
+			if v.Field(i).IsNil() {
+				continue
+			}
+
+			if prefix != env {
+				prefix += "_"
+			}
+
+			settings = append(settings, fields(v.Field(i).Elem(), name+".", prefix)...)
+
+			continue
+		}
+
+		if field.Tag.Get("env") == "" {
+			prefix = ""
+		}
+
+		settings = append(settings, setting{
+			value:  v.Field(i),
+			path:   name,
+			env:    prefix,
+			secret: field.Tag.Get("secret") == "true",
+		})
+	}
+
+	return settings
+}
+
+// set parses raw into v, which is a string, a bool, a number or a time.Duration.
+func set(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(n)
+	default:
+		return errors.Errorf("unsupported setting type %s", v.Type())
+	}
+
+	return nil
+}
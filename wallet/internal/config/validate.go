@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Validate reports every invalid setting at once. It expects all sections to be set, as Load does.
+func (a *Application) Validate() error {
+	problems := make([]string, 0)
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for _, api := range strings.Split(a.Serve, ",") {
+		api = strings.TrimSpace(api)
+		check(api == APIHTTP || api == APIGRPC, "serve %q lists an unknown API %q", a.Serve, api)
+	}
+
+	if a.Serves(APIHTTP) {
+		check(validAddr(a.HTTP.Addr), "http.addr %q is not a host:port", a.HTTP.Addr)
+	}
+
+	if a.Serves(APIGRPC) {
+		check(validAddr(a.GRPC.Addr), "grpc.addr %q is not a host:port", a.GRPC.Addr)
+	}
+
+	check(a.Postgres.DSN != "", "postgres.dsn is not set")
+	check(a.Mongo.DSN != "", "mongo.dsn is not set")
+	check(a.JWT.Secret != "" || a.JWT.SigningKey != "", "jwt.secret or jwt.signing_key must be set")
+	check(a.JWT.SigningKey == "" || a.JWT.KeyID != "", "jwt.key_id is not set for jwt.signing_key")
+	check(a.JWT.Secret == "" || len(a.JWT.Secret) >= MinSecretLength,
+		"jwt.secret must be at least %d bytes long", MinSecretLength)
+
+	if a.JWT.Secret != "" && a.JWT.SigningKey != "" {
+		_, err := a.JWT.SecretExpiry()
+		check(err == nil, "jwt.secret_until must be an RFC 3339 time to keep jwt.secret with jwt.signing_key")
+	}
+
+	kids := map[string]bool{a.JWT.KeyID: a.JWT.SigningKey != ""}
+	for _, file := range a.JWT.VerificationKeyFiles() {
+		check(file.ID != "" && file.Path != "", "jwt.verification_keys must list kid=path pairs")
+		check(!kids[file.ID], "jwt key id %q is used twice", file.ID)
+		kids[file.ID] = true
+	}
+
+	check(a.JWT.AccessTTL > 0, "jwt.access_ttl must be positive")
+	check(a.JWT.RefreshTTL >= a.JWT.AccessTTL, "jwt.refresh_ttl must not be shorter than jwt.access_ttl")
+
+	check(a.Admin.Name == "" || len(a.Admin.Password) >= 8, "admin.password must be at least 8 bytes long")
+
+	check(a.Fee.Percent >= 0 && a.Fee.Percent <= 100, "fee.percent %v is out of range", a.Fee.Percent)
+	check(a.Fee.Min >= 0 && a.Fee.Max >= 0 && (a.Fee.Max == 0 || a.Fee.Max >= a.Fee.Min),
+		"fee caps min=%d max=%d are invalid", a.Fee.Min, a.Fee.Max)
+	check(a.Fee.WalletID != "", "fee.wallet_id is not set")
+
+	switch a.Fee.Rounding {
+	case "", "up", "down", "half_up":
+	default:
+		check(false, "fee.rounding %q is unknown", a.Fee.Rounding)
+	}
+
+	check(a.Queue.Workers > 0, "queue.workers must be positive")
+	check(a.Queue.Capacity > 0, "queue.capacity must be positive")
+	check(a.Queue.MaxWait >= 0, "queue.max_wait must not be negative")
+	check(a.Timeouts.Read >= 0 && a.Timeouts.Write >= 0 && a.Timeouts.Transaction >= 0,
+		"timeouts must not be negative")
+	check(a.Timeouts.Shutdown > 0, "timeouts.shutdown must be positive")
+
+	if len(problems) != 0 {
+		return errors.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func validAddr(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	return err == nil && port != ""
+}
@@ -0,0 +1,101 @@
+package apierror
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// RequestIDHeader carries the request ID. A client may set it, otherwise the server picks one.
+const RequestIDHeader = "X-Request-ID"
+
+// Body is the error object of the API as documented in swagger.
+type Body struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		log.Printf("Unable to generate request id: %v\n", err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx that carries the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID of ctx, or "" when there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID keeps the request ID sent by the client or assigns a new one, and echoes it in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = NewRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// Status maps the kind of an error to an HTTP status code.
+func Status(kind wallet.Kind) int {
+	switch kind {
+	case wallet.KindInvalid:
+		return http.StatusBadRequest
+	case wallet.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case wallet.KindForbidden:
+		return http.StatusForbidden
+	case wallet.KindNotFound:
+		return http.StatusNotFound
+	case wallet.KindConflict:
+		return http.StatusConflict
+	case wallet.KindRejected:
+		return http.StatusUnprocessableEntity
+	case wallet.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case wallet.KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Write replies with the error object of err and logs it with the request ID.
+// The cause of an internal error is only logged.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := wallet.AsError(err)
+	id := RequestIDFrom(r.Context())
+
+	log.Printf("%s %s failed (request %s): %v\n", r.Method, r.URL.Path, id, err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(Status(e.Kind))
+
+	err = json.NewEncoder(w).Encode(Body{Code: e.Code, Message: e.Message, RequestID: id})
+	if err != nil {
+		log.Printf("Unable to encode error: %v\n", err)
+	}
+}
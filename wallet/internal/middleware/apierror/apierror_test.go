@@ -0,0 +1,45 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+//nolint
+func TestWrite(t *testing.T) {
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, errors.New("pq: password authentication failed"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+	assert.JSONEq(t, `{"code":"internal","message":"internal error","requestId":"req-1"}`, w.Body.String())
+}
+
+//nolint
+func TestRequestIDGenerated(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
+
+//nolint
+func TestStatus(t *testing.T) {
+	assert.Equal(t, http.StatusUnprocessableEntity, Status(wallet.AsError(wallet.ErrInsufficientFunds).Kind))
+	assert.Equal(t, http.StatusConflict, Status(wallet.AsError(wallet.ErrAlreadyReversed).Kind))
+	assert.Equal(t, http.StatusServiceUnavailable, Status(wallet.AsError(wallet.ErrQueueFull).Kind))
+	assert.Equal(t, http.StatusForbidden, Status(wallet.AsError(wallet.ErrForbidden).Kind))
+}
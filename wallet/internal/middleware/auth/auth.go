@@ -1,58 +1,93 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
 )
 
+// bearer is the scheme of the Authorization header, RFC 6750.
+const bearer = "Bearer"
+
 type JwtWrapper struct {
-	SecretKey       string
-	ExpirationHours int64
+	// Keys sign the access tokens and verify them.
+	Keys *KeySet
+	// AccessTTL is how long an access token is valid. It is kept short, as a session is
+	// renewed with its refresh token, which is valid for RefreshTTL.
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
 }
 
 type JwtClaim struct {
 	Name string
+	Role string
 	jwt.StandardClaims
 }
 
-func NewJwtWrapper(secretKey string, expirationHours int64) *JwtWrapper {
+// Tokens are the answer to a login or a refresh.
+type Tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	// ExpiresIn is the number of seconds the access token is valid for.
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+// Revocations tells whether an access token was revoked before it expired.
+type Revocations interface {
+	IsAccessTokenRevoked(ctx context.Context, id string) (bool, error)
+}
+
+func NewJwtWrapper(keys *KeySet, accessTTL, refreshTTL time.Duration) *JwtWrapper {
 	return &JwtWrapper{
-		SecretKey:       secretKey,
-		ExpirationHours: expirationHours,
+		Keys:       keys,
+		AccessTTL:  accessTTL,
+		RefreshTTL: refreshTTL,
 	}
 }
 
-func (j *JwtWrapper) GenerateToken(name string) (signedToken string, err error) {
+// GenerateToken issues an access token for the user. The subject claim holds the user ID,
+// the jti claim the id the token is revoked by and the aud claim the backend of the session.
+func (j *JwtWrapper) GenerateToken(userID, name, role, tokenID, audience string) (signedToken string, err error) {
 	claims := &JwtClaim{
 		Name: name,
+		Role: role,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(j.ExpirationHours)).Unix(),
+			Id:        tokenID,
+			Subject:   userID,
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(j.AccessTTL).Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return j.Keys.Sign(claims)
+}
 
-	signedToken, err = token.SignedString([]byte(j.SecretKey))
+// SessionTokens signs the access token of the session for the backend that keeps it and
+// pairs it with the refresh token.
+func (j *JwtWrapper) SessionTokens(session *wallet.Session, audience string) (*Tokens, error) {
+	user := session.User
+
+	token, err := j.GenerateToken(user.ID, user.Name, user.Role, session.AccessTokenID, audience)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return signedToken, nil
+	return &Tokens{Token: token, RefreshToken: session.RefreshToken, ExpiresIn: int64(j.AccessTTL / time.Second)}, nil
 }
 
+// ValidateToken checks the signature of the token with the key named by its kid header.
+// Only the algorithms of the configured keys are accepted.
 func (j *JwtWrapper) ValidateToken(signedToken string) (claims *JwtClaim, err error) {
-	token, err := jwt.ParseWithClaims(
-		signedToken,
-		&JwtClaim{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(j.SecretKey), nil
-		},
-	)
+	parser := &jwt.Parser{ValidMethods: j.Keys.Algorithms()}
+
+	token, err := parser.ParseWithClaims(signedToken, &JwtClaim{}, j.Keys.verificationKey)
 	if err != nil {
 		return
 	}
@@ -67,30 +102,107 @@ func (j *JwtWrapper) ValidateToken(signedToken string) (claims *JwtClaim, err er
 	return
 }
 
-func (j *JwtWrapper) AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if len(tokenString) == 0 {
-			w.WriteHeader(http.StatusUnauthorized)
-			_, err := w.Write([]byte("Missing Authorization Header"))
+// Authenticate validates the access token and returns a copy of ctx that carries its claims
+// and the user it was issued to. The token must be issued for audience, the backend whose
+// revocations are checked, as the other backends do not know its session. Tokens whose session
+// has ended are rejected.
+func (j *JwtWrapper) Authenticate(ctx context.Context, signedToken, audience string, revocations Revocations) (context.Context, error) {
+	claims, err := j.ValidateToken(signedToken)
+	if err != nil {
+		return nil, unauthenticated("invalid token", err)
+	}
+
+	if !claims.VerifyAudience(audience, true) {
+		return nil, unauthenticated("token is issued for another backend", nil)
+	}
+
+	principal, err := claims.Principal()
+	if err != nil {
+		return nil, unauthenticated("invalid token", err)
+	}
+
+	revoked, err := revocations.IsAccessTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, unauthenticated("token is revoked", nil)
+	}
+
+	return wallet.WithPrincipal(WithClaims(ctx, claims), principal), nil
+}
+
+// BearerToken returns the token of an Authorization header or metadata value. The Bearer
+// scheme is matched in any case and may be left out.
+func BearerToken(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) > 0 && strings.EqualFold(fields[0], bearer) {
+		fields = fields[1:]
+	}
+
+	return strings.Join(fields, " ")
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx that carries the claims of the access token.
+func WithClaims(ctx context.Context, claims *JwtClaim) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFrom returns the claims of the access token the call was authenticated with.
+func ClaimsFrom(ctx context.Context) (*JwtClaim, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*JwtClaim)
+	return claims, ok && claims != nil
+}
+
+// AuthMiddleware requires a valid access token and puts its claims and principal in the request context.
+// backend returns the audience of the request and where it checks for revoked tokens, each backend
+// keeps its own sessions.
+func (j *JwtWrapper) AuthMiddleware(backend func(r *http.Request) (string, Revocations, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := BearerToken(r.Header.Get("Authorization"))
+			if len(tokenString) == 0 {
+				apierror.Write(w, r, unauthenticated("missing Authorization header", nil))
+				return
+			}
+
+			audience, revocations, err := backend(r)
 			if err != nil {
-				log.Fatal(err)
+				apierror.Write(w, r, err)
+				return
 			}
 
-			return
-		}
-		tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-		_, err := j.ValidateToken(tokenString)
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			_, err := w.Write([]byte("Error verifying JWT token: " + err.Error()))
+			ctx, err := j.Authenticate(r.Context(), tokenString, audience, revocations)
 			if err != nil {
-				log.Fatal(err)
+				apierror.Write(w, r, err)
+				return
 			}
 
-			return
-		}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Principal returns the user the token was issued to. Tokens issued before roles act for a plain user.
+func (c *JwtClaim) Principal() (*wallet.Principal, error) {
+	switch {
+	case c.Subject == "":
+		return nil, errors.New("token has no subject")
+	case c.Id == "":
+		return nil, errors.New("token has no id")
+	}
+
+	role := c.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	return &wallet.Principal{UserID: c.Subject, Name: c.Name, Role: role}, nil
+}
 
-		next.ServeHTTP(w, r)
-	})
+func unauthenticated(message string, err error) error {
+	return &wallet.Error{Kind: wallet.KindUnauthenticated, Code: "unauthenticated", Message: message, Err: err}
 }
@@ -0,0 +1,24 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/middleware/auth"
+)
+
+//nolint
+func TestBearerToken(t *testing.T) {
+	for value, token := range map[string]string{
+		"Bearer abc.def":   "abc.def",
+		"bearer abc.def":   "abc.def",
+		"BEARER  abc.def ": "abc.def",
+		"abc.def":          "abc.def",
+		"Bearer ":          "",
+		"Bearer":           "",
+		"Bearerabc.def":    "Bearerabc.def",
+		"":                 "",
+	} {
+		assert.Equal(t, token, auth.BearerToken(value), value)
+	}
+}
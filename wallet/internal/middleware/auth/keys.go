@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256.
+const minRSABits = 2048
+
+// Key signs or verifies tokens. Its algorithm follows from the type of the key, so a token
+// is only checked with the algorithm its key was made for.
+type Key struct {
+	// ID is the kid header of the tokens signed with the key. The shared secret has none.
+	ID     string
+	Method jwt.SigningMethod
+	// private is nil for a key that only verifies.
+	private interface{}
+	public  interface{}
+	// expires is when the key stops verifying, zero for never.
+	expires time.Time
+}
+
+// NewSecretKey returns the HS256 key of a shared secret. It is never published.
+func NewSecretKey(secret string) *Key {
+	return &Key{Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+}
+
+// LoadKey reads an RSA or Ed25519 key from a PEM file. A private key can sign and verify,
+// a public key only verifies.
+func LoadKey(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "jwt key")
+	}
+
+	key, err := ParseKey(id, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "jwt key %s", path)
+	}
+
+	return key, nil
+}
+
+// ParseKey parses a PEM encoded RSA or Ed25519 key, private or public.
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.Errorf("unsupported key type %T", parsed)
+	}
+
+	if public, ok := key.public.(*rsa.PublicKey); ok && public.N.BitLen() < minRSABits {
+		return nil, errors.Errorf("RSA key has %d bits, at least %d are required", public.N.BitLen(), minRSABits)
+	}
+
+	return key, nil
+}
+
+// Until returns a copy of the key that stops verifying tokens at t.
+func (k *Key) Until(t time.Time) *Key {
+	key := *k
+	key.expires = t
+
+	return &key
+}
+
+// CanSign reports whether the key holds a private key or a secret.
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+// KeySet holds the key tokens are signed with and every key they are still accepted with.
+// Keys are rotated by signing with a new key while the old one keeps verifying, until the
+// tokens it signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    []*Key
+}
+
+// NewKeySet signs with signing, which verifies too, and also verifies with the other keys.
+func NewKeySet(signing *Key, verification ...*Key) *KeySet {
+	return &KeySet{signing: signing, keys: append([]*Key{signing}, verification...)}
+}
+
+// Sign signs the claims with the signing key and names it in the kid header.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if !k.signing.CanSign() {
+		return "", errors.New("signing key has no private key")
+	}
+
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+
+	return token.SignedString(k.signing.private)
+}
+
+// Algorithms lists the algorithms of the keys, the only ones a token may be signed with.
+func (k *KeySet) Algorithms() []string {
+	algs := make([]string, 0, len(k.keys))
+	seen := make(map[string]bool)
+
+	for _, key := range k.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+
+	return algs
+}
+
+// verificationKey picks the key named by the kid header of the token. The key must be of
+// the algorithm of the token, so a public key is never used as an HMAC secret.
+func (k *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	now := time.Now()
+
+	for _, key := range k.keys {
+		if !key.expires.IsZero() && !now.Before(key.expires) {
+			continue
+		}
+
+		if key.ID == kid && key.Method.Alg() == token.Method.Alg() {
+			return key.public, nil
+		}
+	}
+
+	return nil, errors.Errorf("no %s key with id %q", token.Method.Alg(), kid)
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// N and E are the modulus and exponent of an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are the curve and public key of an Ed25519 key.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document other services fetch the keys from.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. The shared secret is left out.
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+
+	for _, key := range k.keys {
+		jwk := JWK{Use: "sig", Alg: key.Method.Alg(), Kid: key.ID}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
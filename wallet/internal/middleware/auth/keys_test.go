@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/middleware/auth"
+)
+
+func rsaKey(t *testing.T, id string) *auth.Key {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	key, err := auth.ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	return key
+}
+
+func edKey(t *testing.T, id string) (*auth.Key, []byte) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	key, err := auth.ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	der, err = x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+//nolint
+func TestSigningKeys(t *testing.T) {
+	for _, key := range []*auth.Key{rsaKey(t, "rsa-1"), func() *auth.Key { k, _ := edKey(t, "ed-1"); return k }()} {
+		wrapper := auth.NewJwtWrapper(auth.NewKeySet(key), time.Hour, 24*time.Hour)
+
+		token, err := wrapper.GenerateToken("user-1", "alice", "user", "token-1", "postgre")
+		require.NoError(t, err)
+
+		parsed, _, err := new(jwt.Parser).ParseUnverified(token, &auth.JwtClaim{})
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, parsed.Header["kid"])
+		assert.Equal(t, key.Method.Alg(), parsed.Header["alg"])
+
+		claims, err := wrapper.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", claims.Subject)
+	}
+}
+
+//nolint
+func TestKeyRotation(t *testing.T) {
+	old, oldPublic := edKey(t, "2022-01")
+	next := rsaKey(t, "2022-02")
+	secret := auth.NewSecretKey("verysecretkey")
+
+	before, err := auth.NewJwtWrapper(auth.NewKeySet(old), time.Hour, 24*time.Hour).GenerateToken("user-1", "alice", "user", "token-1", "postgre")
+	require.NoError(t, err)
+	hmac, err := auth.NewJwtWrapper(auth.NewKeySet(secret), time.Hour, 24*time.Hour).GenerateToken("user-1", "alice", "user", "token-2", "postgre")
+	require.NoError(t, err)
+
+	retired, err := auth.ParseKey("2022-01", oldPublic)
+	require.NoError(t, err)
+	assert.False(t, retired.CanSign())
+
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(next, retired, secret), time.Hour, 24*time.Hour)
+	after, err := wrapper.GenerateToken("user-1", "alice", "user", "token-3", "postgre")
+	require.NoError(t, err)
+
+	for _, token := range []string{before, hmac, after} {
+		_, err = wrapper.ValidateToken(token)
+		assert.NoError(t, err)
+	}
+
+	_, err = auth.NewJwtWrapper(auth.NewKeySet(next), time.Hour, 24*time.Hour).ValidateToken(before)
+	assert.Error(t, err)
+
+	_, err = auth.NewKeySet(retired).Sign(jwt.StandardClaims{})
+	assert.Error(t, err)
+}
+
+//nolint
+func TestExpiredKey(t *testing.T) {
+	secret := auth.NewSecretKey("verysecretkey")
+	token, err := auth.NewJwtWrapper(auth.NewKeySet(secret), time.Hour, 24*time.Hour).GenerateToken("user-1", "alice", "user", "token-1", "postgre")
+	require.NoError(t, err)
+
+	next := rsaKey(t, "2022-02")
+
+	_, err = auth.NewJwtWrapper(auth.NewKeySet(next, secret.Until(time.Now().Add(time.Hour))), time.Hour, 24*time.Hour).ValidateToken(token)
+	assert.NoError(t, err)
+
+	_, err = auth.NewJwtWrapper(auth.NewKeySet(next, secret.Until(time.Now().Add(-time.Second))), time.Hour, 24*time.Hour).ValidateToken(token)
+	assert.Error(t, err)
+}
+
+//nolint
+func TestPinnedAlgorithms(t *testing.T) {
+	key, public := edKey(t, "ed-1")
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(key), time.Hour, 24*time.Hour)
+	claims := &auth.JwtClaim{StandardClaims: jwt.StandardClaims{Subject: "user-1", Id: "token-1"}}
+
+	// The public key is known to everyone, it must not pass as an HMAC secret.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "ed-1"
+	token, err := forged.SignedString(public)
+	require.NoError(t, err)
+	_, err = wrapper.ValidateToken(token)
+	assert.Error(t, err)
+
+	token, err = jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = wrapper.ValidateToken(token)
+	assert.Error(t, err)
+
+	other, _ := edKey(t, "ed-2")
+	token, err = auth.NewKeySet(other).Sign(claims)
+	require.NoError(t, err)
+	_, err = wrapper.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+//nolint
+func TestJWKS(t *testing.T) {
+	ed, _ := edKey(t, "ed-1")
+	keys := auth.NewKeySet(rsaKey(t, "rsa-1"), ed, auth.NewSecretKey("verysecretkey")).JWKS()
+
+	require.Len(t, keys.Keys, 2)
+	assert.Equal(t, "RSA", keys.Keys[0].Kty)
+	assert.Equal(t, "RS256", keys.Keys[0].Alg)
+	assert.Equal(t, "rsa-1", keys.Keys[0].Kid)
+	assert.Equal(t, "AQAB", keys.Keys[0].E)
+	assert.NotEmpty(t, keys.Keys[0].N)
+	assert.Equal(t, auth.JWK{Kty: "OKP", Use: "sig", Alg: "EdDSA", Kid: "ed-1", Crv: "Ed25519", X: keys.Keys[1].X}, keys.Keys[1])
+	assert.Len(t, keys.Keys[1].X, 43)
+}
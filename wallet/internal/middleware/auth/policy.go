@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// Policy tells who may call an operation. The HTTP routes and the gRPC methods share the
+// policies below, so both APIs grant the same access.
+type Policy struct {
+	public bool
+	roles  []string
+}
+
+var (
+	// Public operations are called without a token, they are how a client gets one.
+	Public = Policy{public: true}
+	// Authenticated operations need a valid token of any role.
+	Authenticated = Policy{}
+	// Staff operations see or change the data of every user: system-wide listings and reversals.
+	Staff = Roles(models.RoleSupport, models.RoleAdmin)
+	// Admin operations manage users.
+	Admin = Roles(models.RoleAdmin)
+)
+
+// Roles restricts an operation to the users with one of the roles.
+func Roles(roles ...string) Policy {
+	return Policy{roles: roles}
+}
+
+// IsPublic reports whether the operation needs no token.
+func (p Policy) IsPublic() bool {
+	return p.public
+}
+
+// Authorize checks that the caller may call the operation. It returns an unauthenticated error
+// when a token is required and there is no caller, and ErrForbidden when the role does not fit.
+func (p Policy) Authorize(principal *wallet.Principal) error {
+	switch {
+	case p.public:
+		return nil
+	case principal == nil:
+		return unauthenticated("authorization token is not provided", nil)
+	case len(p.roles) != 0 && !principal.HasRole(p.roles...):
+		return wallet.ErrForbidden
+	}
+
+	return nil
+}
+
+// Require checks the principal put in the request context by AuthMiddleware against the policy.
+func Require(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := wallet.PrincipalFrom(r.Context())
+
+			err := policy.Authorize(principal)
+			if err != nil {
+				apierror.Write(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
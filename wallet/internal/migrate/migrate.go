@@ -0,0 +1,152 @@
+// Package migrate applies versioned schema migrations. The storage specific work is done by a Driver,
+// this package decides which migrations run and in which order.
+package migrate
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Latest targets the highest known version.
+const Latest = -1
+
+var (
+	// ErrUnknownVersion is returned for a target that is neither 0 nor the version of a migration.
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrIrreversible is returned when a migration that has to be reverted has no down step.
+	ErrIrreversible = errors.New("migration cannot be reverted")
+)
+
+// Migration identifies a schema change. Versions are positive and unique.
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// State tells whether a migration is applied.
+type State struct {
+	Migration
+	Applied bool
+}
+
+// Driver runs migrations against one database.
+type Driver interface {
+	// Migrations returns every known migration.
+	Migrations() []Migration
+	// Lock keeps other migrators out until the returned func is called.
+	Lock(ctx context.Context) (func(), error)
+	// Applied returns the versions recorded as applied.
+	Applied(ctx context.Context) (map[int]bool, error)
+	// Up applies the migration and records it.
+	Up(ctx context.Context, m Migration) error
+	// Down reverts the migration and forgets it. It returns ErrIrreversible when it cannot.
+	Down(ctx context.Context, m Migration) error
+}
+
+// To migrates the database to target: newer migrations are reverted, newest first, then
+// missing ones up to target are applied, oldest first. Target 0 reverts everything.
+func To(ctx context.Context, d Driver, target int) error {
+	migrations := sorted(d)
+
+	if target == Latest {
+		target = 0
+		if len(migrations) != 0 {
+			target = migrations[len(migrations)-1].Version
+		}
+	}
+
+	if target != 0 && !known(migrations, target) {
+		return errors.Wrapf(ErrUnknownVersion, "version %d", target)
+	}
+
+	unlock, err := d.Lock(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	applied, err := d.Applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target || !applied[m.Version] {
+			continue
+		}
+
+		err = d.Down(ctx, m)
+		if err != nil {
+			return errors.Wrapf(err, "revert %d_%s", m.Version, m.Name)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version > target || applied[m.Version] {
+			continue
+		}
+
+		err = d.Up(ctx, m)
+		if err != nil {
+			return errors.Wrapf(err, "apply %d_%s", m.Version, m.Name)
+		}
+	}
+
+	return nil
+}
+
+// Previous returns the version before the newest applied one, the target that reverts a single migration.
+func Previous(ctx context.Context, d Driver) (int, error) {
+	applied, err := d.Applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	previous, last := 0, 0
+
+	for _, m := range sorted(d) {
+		if applied[m.Version] {
+			previous, last = last, m.Version
+		}
+	}
+
+	return previous, nil
+}
+
+// Status lists every migration, oldest first, and whether it is applied.
+func Status(ctx context.Context, d Driver) ([]State, error) {
+	applied, err := d.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := sorted(d)
+	states := make([]State, 0, len(migrations))
+
+	for _, m := range migrations {
+		states = append(states, State{Migration: m, Applied: applied[m.Version]})
+	}
+
+	return states, nil
+}
+
+func sorted(d Driver) []Migration {
+	migrations := append([]Migration(nil), d.Migrations()...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations
+}
+
+func known(migrations []Migration, version int) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,130 @@
+package migrate_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/migrate"
+)
+
+// driver records the steps it runs. Migrations listed in irreversible have no down step.
+type driver struct {
+	migrations   []migrate.Migration
+	applied      map[int]bool
+	irreversible map[int]bool
+	steps        []string
+	locked       bool
+}
+
+func newDriver(applied ...int) *driver {
+	d := &driver{
+		migrations: []migrate.Migration{{Version: 3, Name: "c"}, {Version: 1, Name: "a"}, {Version: 2, Name: "b"}},
+		applied:    make(map[int]bool),
+	}
+
+	for _, version := range applied {
+		d.applied[version] = true
+	}
+
+	return d
+}
+
+func (d *driver) Migrations() []migrate.Migration {
+	return d.migrations
+}
+
+func (d *driver) Lock(context.Context) (func(), error) {
+	d.locked = true
+	return func() { d.locked = false }, nil
+}
+
+func (d *driver) Applied(context.Context) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	for version := range d.applied {
+		applied[version] = true
+	}
+
+	return applied, nil
+}
+
+func (d *driver) Up(_ context.Context, m migrate.Migration) error {
+	if !d.locked {
+		return fmt.Errorf("not locked")
+	}
+
+	d.steps = append(d.steps, fmt.Sprintf("up %d", m.Version))
+	d.applied[m.Version] = true
+
+	return nil
+}
+
+func (d *driver) Down(_ context.Context, m migrate.Migration) error {
+	if d.irreversible[m.Version] {
+		return migrate.ErrIrreversible
+	}
+
+	d.steps = append(d.steps, fmt.Sprintf("down %d", m.Version))
+	delete(d.applied, m.Version)
+
+	return nil
+}
+
+//nolint
+func TestToLatest(t *testing.T) {
+	d := newDriver(1)
+
+	err := migrate.To(context.Background(), d, migrate.Latest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"up 2", "up 3"}, d.steps)
+	assert.False(t, d.locked)
+}
+
+//nolint
+func TestToOlderVersion(t *testing.T) {
+	d := newDriver(1, 2, 3)
+
+	err := migrate.To(context.Background(), d, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"down 3", "down 2"}, d.steps)
+}
+
+//nolint
+func TestToUnknownVersion(t *testing.T) {
+	err := migrate.To(context.Background(), newDriver(), 4)
+
+	assert.ErrorIs(t, err, migrate.ErrUnknownVersion)
+}
+
+//nolint
+func TestToIrreversible(t *testing.T) {
+	d := newDriver(1, 2, 3)
+	d.irreversible = map[int]bool{2: true}
+
+	err := migrate.To(context.Background(), d, 0)
+
+	assert.ErrorIs(t, err, migrate.ErrIrreversible)
+	assert.Equal(t, []string{"down 3"}, d.steps)
+}
+
+//nolint
+func TestPreviousAndStatus(t *testing.T) {
+	d := newDriver(1, 2)
+
+	previous, err := migrate.Previous(context.Background(), d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, previous)
+
+	states, err := migrate.Status(context.Background(), d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []migrate.State{
+		{Migration: migrate.Migration{Version: 1, Name: "a"}, Applied: true},
+		{Migration: migrate.Migration{Version: 2, Name: "b"}, Applied: true},
+		{Migration: migrate.Migration{Version: 3, Name: "c"}, Applied: false},
+	}, states)
+}
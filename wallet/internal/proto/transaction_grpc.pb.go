@@ -21,6 +21,7 @@ type TransactionServiceClient interface {
 	GetTransactions(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
 	CreateTransaction(ctx context.Context, in *CreateTransactionRequest, opts ...grpc.CallOption) (*CreateTransactionResponse, error)
 	GetWalletTransactionsById(ctx context.Context, in *GetWalletTransactionsByIdRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
+	ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*ReverseTransactionResponse, error)
 }
 
 type transactionServiceClient struct {
@@ -58,6 +59,15 @@ func (c *transactionServiceClient) GetWalletTransactionsById(ctx context.Context
 	return out, nil
 }
 
+func (c *transactionServiceClient) ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*ReverseTransactionResponse, error) {
+	out := new(ReverseTransactionResponse)
+	err := c.cc.Invoke(ctx, "/transaction.TransactionService/ReverseTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TransactionServiceServer is the server API for TransactionService service.
 // All implementations must embed UnimplementedTransactionServiceServer
 // for forward compatibility
@@ -65,6 +75,7 @@ type TransactionServiceServer interface {
 	GetTransactions(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
 	CreateTransaction(context.Context, *CreateTransactionRequest) (*CreateTransactionResponse, error)
 	GetWalletTransactionsById(context.Context, *GetWalletTransactionsByIdRequest) (*GetTransactionResponse, error)
+	ReverseTransaction(context.Context, *ReverseTransactionRequest) (*ReverseTransactionResponse, error)
 	mustEmbedUnimplementedTransactionServiceServer()
 }
 
@@ -81,6 +92,9 @@ func (UnimplementedTransactionServiceServer) CreateTransaction(context.Context,
 func (UnimplementedTransactionServiceServer) GetWalletTransactionsById(context.Context, *GetWalletTransactionsByIdRequest) (*GetTransactionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetWalletTransactionsByID not implemented")
 }
+func (UnimplementedTransactionServiceServer) ReverseTransaction(context.Context, *ReverseTransactionRequest) (*ReverseTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReverseTransaction not implemented")
+}
 func (UnimplementedTransactionServiceServer) mustEmbedUnimplementedTransactionServiceServer() {}
 
 // UnsafeTransactionServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -148,6 +162,24 @@ func _TransactionService_GetWalletTransactionsById_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TransactionService_ReverseTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReverseTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).ReverseTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transaction.TransactionService/ReverseTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).ReverseTransaction(ctx, req.(*ReverseTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TransactionService_ServiceDesc is the grpc.ServiceDesc for TransactionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -167,6 +199,10 @@ var TransactionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetWalletTransactionsByID",
 			Handler:    _TransactionService_GetWalletTransactionsById_Handler,
 		},
+		{
+			MethodName: "ReverseTransaction",
+			Handler:    _TransactionService_ReverseTransaction_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "transaction.proto",
@@ -0,0 +1,644 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// dateLayout matches how the Postgres driver renders timestamps, so all backends return the same dates.
+const dateLayout = time.RFC3339Nano
+
+// Repository keeps everything in process memory. It is safe for concurrent use and applies
+// a transfer with the same checks as the database backends, all or nothing.
+type Repository struct {
+	mu           sync.RWMutex
+	users        map[string]*models.User
+	wallets      map[string]*models.Wallet
+	transactions []*record
+	byID         map[string]*record
+	byKey        map[idempotencyKey]*record
+	entries      []*models.LedgerEntry
+	refresh      map[string]*models.RefreshToken
+	byAccess     map[string]*models.RefreshToken
+}
+
+// idempotencyKey is unique per sending wallet.
+type idempotencyKey struct {
+	walletID string
+	key      string
+}
+
+// record is a stored transaction with its parsed date, transactions are kept ordered by date and id.
+type record struct {
+	date        time.Time
+	transaction *models.Transaction
+}
+
+// NewRepository creates an empty repository holding the wallets. Seeded wallets keep their ids,
+// as the fee wallet has to exist before the first transfer.
+func NewRepository(wallets ...*models.Wallet) *Repository {
+	r := &Repository{
+		users:    make(map[string]*models.User),
+		wallets:  make(map[string]*models.Wallet),
+		byID:     make(map[string]*record),
+		byKey:    make(map[idempotencyKey]*record),
+		refresh:  make(map[string]*models.RefreshToken),
+		byAccess: make(map[string]*models.RefreshToken),
+	}
+
+	for _, w := range wallets {
+		seeded := *w
+		seeded.ID = strings.ToLower(seeded.ID)
+		r.wallets[seeded.ID] = &seeded
+
+		if _, ok := r.users[w.UserID]; !ok && w.UserID != "" {
+			r.users[w.UserID] = &models.User{ID: w.UserID, Role: models.RoleUser}
+		}
+	}
+
+	return r
+}
+
+// newID returns a random version 4 UUID, the id format of the Postgres backend.
+func newID() string {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.PasswordHash != "" {
+		if _, ok := r.userByName(user.Name); ok {
+			return wallet.ErrUserExists
+		}
+	}
+
+	user.ID = newID()
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	created := *user
+	r.users[created.ID] = &created
+
+	return nil
+}
+
+func (r *Repository) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.userByName(name)
+	if !ok {
+		return nil, wallet.ErrUserNotFound
+	}
+
+	found := *user
+
+	return &found, nil
+}
+
+// userByName finds the user with a password that has the name.
+func (r *Repository) userByName(name string) (*models.User, bool) {
+	for _, user := range r.users {
+		if user.Name == name && user.PasswordHash != "" {
+			return user, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *Repository) GetUsers(ctx context.Context, page models.Pagination) ([]*models.User, *models.PageInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.users))
+	for id := range r.users {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	info := &models.PageInfo{Total: len(ids)}
+
+	if page.Cursor != "" {
+		key, ok := models.DecodeCursor(page.Cursor, 1)
+		if !ok {
+			return nil, nil, wallet.ErrInvalidCursor
+		}
+
+		ids = ids[sort.Search(len(ids), func(i int) bool { return ids[i] > key[0] }):]
+	}
+
+	from, to := window(len(ids), page)
+	ids = ids[from:to]
+
+	users := make([]*models.User, 0, len(ids))
+
+	for _, id := range ids {
+		user := *r.users[id]
+		user.PasswordHash = ""
+		users = append(users, &user)
+	}
+
+	if page.Limit > 0 && len(users) > page.Limit {
+		users = users[:page.Limit]
+		info.NextCursor = models.EncodeCursor(users[page.Limit-1].ID)
+	}
+
+	return users, info, nil
+}
+
+// window returns the bounds of the page within n items. It keeps one item more than the limit,
+// which tells whether there is a next page. A zero limit keeps every item after the offset.
+func window(n int, page models.Pagination) (int, int) {
+	from := page.Offset
+	if from > n {
+		from = n
+	}
+
+	to := from + page.Limit + 1
+	if page.Limit <= 0 || to > n {
+		to = n
+	}
+
+	return from, to
+}
+
+// StreamUsers walks a copy of the users, as there is no database to read them from.
+func (r *Repository) StreamUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error {
+	users, _, err := r.GetUsers(ctx, page)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err = fn(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateWallet(ctx context.Context, w *models.Wallet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if w.Balance < 0 {
+		return wallet.ErrInsufficientFunds
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.ID = newID()
+	created := *w
+	r.wallets[created.ID] = &created
+
+	r.appendEntries(wallet.OpeningEntries(w), time.Now())
+
+	return nil
+}
+
+func (r *Repository) GetWalletByID(ctx context.Context, id string) (*models.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.wallets[strings.ToLower(id)]
+	if !ok {
+		return nil, wallet.ErrWalletNotFound
+	}
+
+	found := *w
+
+	return &found, nil
+}
+
+func (r *Repository) GetWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	return r.listTransactions(ctx, strings.ToLower(id), filter, page)
+}
+
+func (r *Repository) GetTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	return r.listTransactions(ctx, "", filter, page)
+}
+
+func (r *Repository) StreamWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	return r.streamTransactions(ctx, strings.ToLower(id), filter, page, fn)
+}
+
+func (r *Repository) StreamTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	return r.streamTransactions(ctx, "", filter, page, fn)
+}
+
+// streamTransactions walks a copy of the matching transactions, so fn runs without holding the lock.
+func (r *Repository) streamTransactions(ctx context.Context, walletID string, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	transactions, _, err := r.listTransactions(ctx, walletID, filter, page)
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listTransactions returns a page of the transactions matching the filter, ordered by date and id.
+// The keyset cursor holds the date and id of the last transaction of the previous page.
+func (r *Repository) listTransactions(ctx context.Context, walletID string, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		after   *record
+		matched []*record
+	)
+
+	if page.Cursor != "" {
+		key, ok := models.DecodeCursor(page.Cursor, 2)
+		if !ok {
+			return nil, nil, wallet.ErrInvalidCursor
+		}
+
+		date, err := time.Parse(dateLayout, key[0])
+		if err != nil {
+			return nil, nil, wallet.ErrInvalidCursor
+		}
+
+		after = &record{date: date, transaction: &models.Transaction{ID: key[1]}}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info := new(models.PageInfo)
+
+	for _, rec := range r.transactions {
+		if !matches(walletID, filter, rec) {
+			continue
+		}
+
+		info.Total++
+
+		if after == nil || less(after, rec) {
+			matched = append(matched, rec)
+		}
+	}
+
+	from, to := window(len(matched), page)
+	matched = matched[from:to]
+
+	transactions := make([]*models.Transaction, 0, len(matched))
+
+	for _, rec := range matched {
+		transaction := *rec.transaction
+		transactions = append(transactions, &transaction)
+	}
+
+	if page.Limit > 0 && len(transactions) > page.Limit {
+		transactions = transactions[:page.Limit]
+		last := transactions[page.Limit-1]
+		info.NextCursor = models.EncodeCursor(last.Date, last.ID)
+	}
+
+	return transactions, info, nil
+}
+
+func less(a, b *record) bool {
+	if a.date.Equal(b.date) {
+		return a.transaction.ID < b.transaction.ID
+	}
+
+	return a.date.Before(b.date)
+}
+
+// matches reports whether the transaction passes the filter. When walletID is set, only its
+// transactions match and the counterparty is the other side.
+func matches(walletID string, filter models.TransactionFilter, rec *record) bool {
+	t := rec.transaction
+
+	if walletID != "" {
+		outgoing := t.CreditWalletID == walletID
+		incoming := t.DebitWalletID == walletID
+
+		switch filter.Direction {
+		case models.DirectionIncoming:
+			outgoing = false
+		case models.DirectionOutgoing:
+			incoming = false
+		}
+
+		if !outgoing && !incoming {
+			return false
+		}
+
+		if filter.CounterpartyWalletID != "" &&
+			!(outgoing && t.DebitWalletID == filter.CounterpartyWalletID) &&
+			!(incoming && t.CreditWalletID == filter.CounterpartyWalletID) {
+			return false
+		}
+
+		if filter.CounterpartyUserID != "" &&
+			!(outgoing && t.DebitUserID == filter.CounterpartyUserID) &&
+			!(incoming && t.CreditUserID == filter.CounterpartyUserID) {
+			return false
+		}
+	} else {
+		if filter.CounterpartyWalletID != "" &&
+			t.CreditWalletID != filter.CounterpartyWalletID && t.DebitWalletID != filter.CounterpartyWalletID {
+			return false
+		}
+
+		if filter.CounterpartyUserID != "" &&
+			t.CreditUserID != filter.CounterpartyUserID && t.DebitUserID != filter.CounterpartyUserID {
+			return false
+		}
+	}
+
+	switch {
+	case !filter.DateFrom.IsZero() && rec.date.Before(filter.DateFrom),
+		!filter.DateTo.IsZero() && rec.date.After(filter.DateTo),
+		filter.MinAmount != 0 && t.Amount < filter.MinAmount,
+		filter.MaxAmount != 0 && t.Amount > filter.MaxAmount,
+		filter.Type != nil && t.Type != *filter.Type,
+		filter.FeeWalletID != "" && t.FeeWalletID != filter.FeeWalletID,
+		filter.UserID != "" && t.CreditUserID != filter.UserID && t.DebitUserID != filter.UserID:
+		return false
+	}
+
+	return true
+}
+
+// CreateTransaction checks everything the transfer needs before it changes anything,
+// so a failed transfer leaves no trace.
+func (r *Repository) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if transaction.Amount <= 0 {
+		return wallet.ErrInvalidAmount
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := *transaction
+	created.CreditWalletID = strings.ToLower(created.CreditWalletID)
+	created.DebitWalletID = strings.ToLower(created.DebitWalletID)
+	created.FeeWalletID = strings.ToLower(created.FeeWalletID)
+
+	for _, id := range []string{created.CreditWalletID, created.DebitWalletID, created.FeeWalletID} {
+		if _, ok := r.wallets[id]; !ok {
+			return wallet.ErrWalletNotFound
+		}
+	}
+
+	now := time.Now().UTC()
+	created.ID = newID()
+	created.Date = now.Format(dateLayout)
+	created.CreditUserID = r.wallets[created.CreditWalletID].UserID
+	created.DebitUserID = r.wallets[created.DebitWalletID].UserID
+	created.RefundedAmount = 0
+	created.RefundedFeeAmount = 0
+	created.RefundedFeeWalletAmount = 0
+
+	entries := wallet.TransferEntries(&created)
+	balances := make(map[string]int)
+
+	for _, entry := range entries {
+		if !wallet.IsWalletAccount(entry.Account) {
+			continue
+		}
+
+		balance, ok := balances[entry.Account]
+		if !ok {
+			balance = r.wallets[entry.Account].Balance
+		}
+
+		balances[entry.Account] = balance + entry.Amount
+		if balances[entry.Account] < 0 {
+			return wallet.ErrInsufficientFunds
+		}
+	}
+
+	var original *models.Transaction
+
+	if created.OriginalTransactionID != "" {
+		rec, ok := r.byID[strings.ToLower(created.OriginalTransactionID)]
+		if !ok {
+			return wallet.ErrTransactionNotFound
+		}
+
+		refunded := *rec.transaction
+		refunded.RefundedAmount += created.DebitAmount
+		refunded.RefundedFeeAmount += created.FeeAmount
+		refunded.RefundedFeeWalletAmount += created.FeeWalletAmount
+
+		err := wallet.CheckRefunds(&refunded, &created)
+		if err != nil {
+			return err
+		}
+
+		original = &refunded
+	}
+
+	if created.IdempotencyKey != "" {
+		if _, ok := r.byKey[idempotencyKey{created.CreditWalletID, created.IdempotencyKey}]; ok {
+			return wallet.ErrDuplicateIdempotencyKey
+		}
+	}
+
+	for id, balance := range balances {
+		r.wallets[id].Balance = balance
+	}
+
+	if original != nil {
+		*r.byID[original.ID].transaction = *original
+	}
+
+	stored := created
+	rec := &record{date: now, transaction: &stored}
+	r.insert(rec)
+
+	r.appendEntries(entries, now)
+
+	*transaction = created
+
+	return nil
+}
+
+// insert keeps the transactions ordered by date and id.
+func (r *Repository) insert(rec *record) {
+	i := sort.Search(len(r.transactions), func(i int) bool {
+		return less(rec, r.transactions[i])
+	})
+
+	r.transactions = append(r.transactions, nil)
+	copy(r.transactions[i+1:], r.transactions[i:])
+	r.transactions[i] = rec
+
+	r.byID[rec.transaction.ID] = rec
+
+	if rec.transaction.IdempotencyKey != "" {
+		r.byKey[idempotencyKey{rec.transaction.CreditWalletID, rec.transaction.IdempotencyKey}] = rec
+	}
+}
+
+func (r *Repository) appendEntries(entries []*models.LedgerEntry, date time.Time) {
+	for _, entry := range entries {
+		stored := *entry
+		stored.ID = strconv.Itoa(len(r.entries) + 1)
+		stored.Date = date.UTC().Format(dateLayout)
+		r.entries = append(r.entries, &stored)
+	}
+}
+
+func (r *Repository) GetLedgerEntriesByWalletID(ctx context.Context, id string) ([]*models.LedgerEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account := strings.ToLower(id)
+	entries := make([]*models.LedgerEntry, 0)
+	balance := 0
+
+	for _, entry := range r.entries {
+		if entry.Account != account {
+			continue
+		}
+
+		balance += entry.Amount
+
+		found := *entry
+		found.Balance = balance
+		entries = append(entries, &found)
+	}
+
+	return entries, nil
+}
+
+func (r *Repository) GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rec, ok := r.byID[strings.ToLower(id)]
+	if !ok {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	transaction := *rec.transaction
+
+	return &transaction, nil
+}
+
+func (r *Repository) GetTransactionByIdempotencyKey(ctx context.Context, walletID, key string) (*models.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Wallet ids are stored lowercased, as Postgres compares uuids whatever their case.
+	rec, ok := r.byKey[idempotencyKey{strings.ToLower(walletID), key}]
+	if !ok {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	transaction := *rec.transaction
+
+	return &transaction, nil
+}
+
+func (r *Repository) GetWalletAmountDayByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, wallet.PeriodDay)
+}
+
+func (r *Repository) GetWalletAmountWeekByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, wallet.PeriodWeek)
+}
+
+func (r *Repository) aggregate(ctx context.Context, id string, week models.Week, period string) ([]*models.Day, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	from, to, err := wallet.ParseWeek(week)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aggregation := wallet.NewAggregation(strings.ToLower(id), period)
+
+	for _, rec := range r.transactions {
+		if rec.date.Before(from) || rec.date.After(to) {
+			continue
+		}
+
+		aggregation.Add(rec.transaction, rec.date)
+	}
+
+	return aggregation.Days(), nil
+}
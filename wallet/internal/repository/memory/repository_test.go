@@ -0,0 +1,186 @@
+package memory_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/config"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/repository/repotest"
+	"github.com/workshops/wallet/internal/services/fee"
+	"github.com/workshops/wallet/internal/services/rate"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+const feeWalletID = "85aa7525-4fdb-4436-a600-66ffc55e0f65"
+
+func newService(t *testing.T, repo wallet.Repository) *wallet.Service {
+	fees, err := fee.NewPolicy(&config.Fee{Percent: 1.5, WalletID: feeWalletID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srvc := wallet.NewService(repo, fees, rate.NewStaticProvider("USD", nil), wallet.QueueConfig{Workers: 4},
+		wallet.Timeouts{})
+	t.Cleanup(srvc.Close)
+
+	return srvc
+}
+
+func newWallet(t *testing.T, srvc *wallet.Service, balance int) string {
+	w := &models.Wallet{Balance: balance, UserID: "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a"}
+
+	err := srvc.CreateWallet(context.Background(), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w.ID
+}
+
+func balance(t *testing.T, srvc *wallet.Service, id string) int {
+	w, err := srvc.GetWalletByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Balance
+}
+
+//nolint
+func TestCreateTransaction(t *testing.T) {
+	repo := memory.NewRepository(&models.Wallet{ID: feeWalletID, UserID: "66aeb414-335a-4d1d-9dd9-6622b9c179a9", Currency: "USD"})
+	srvc := newService(t, repo)
+	ctx := context.Background()
+
+	from := newWallet(t, srvc, 2000)
+	to := newWallet(t, srvc, 0)
+
+	transaction := &models.Transaction{CreditWalletID: from, DebitWalletID: to, Amount: 1000}
+
+	err := srvc.CreateTransaction(ctx, transaction)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, transaction.ID)
+	assert.Equal(t, 15, transaction.FeeAmount)
+	assert.Equal(t, 985, balance(t, srvc, from))
+	assert.Equal(t, 1000, balance(t, srvc, to))
+	assert.Equal(t, 15, balance(t, srvc, feeWalletID))
+
+	entries, err := srvc.GetWalletLedger(ctx, from)
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, 985, entries[1].Balance)
+
+	stored, err := repo.GetTransactionByID(ctx, transaction.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, transaction, stored)
+}
+
+//nolint
+func TestCreateTransactionInsufficientFunds(t *testing.T) {
+	srvc := newService(t, memory.NewRepository(&models.Wallet{ID: feeWalletID, Currency: "USD"}))
+	ctx := context.Background()
+
+	from := newWallet(t, srvc, 1000)
+	to := newWallet(t, srvc, 0)
+
+	err := srvc.CreateTransaction(ctx, &models.Transaction{CreditWalletID: from, DebitWalletID: to, Amount: 1000})
+
+	assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+	assert.Equal(t, 1000, balance(t, srvc, from))
+	assert.Equal(t, 0, balance(t, srvc, to))
+
+	transactions, info, err := srvc.GetTransactions(ctx, models.TransactionFilter{}, models.Pagination{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+	assert.Equal(t, 0, info.Total)
+}
+
+//nolint
+func TestConcurrentTransactions(t *testing.T) {
+	srvc := newService(t, memory.NewRepository(&models.Wallet{ID: feeWalletID, Currency: "USD"}))
+	ctx := context.Background()
+
+	from := newWallet(t, srvc, 10150)
+	to := newWallet(t, srvc, 0)
+
+	var wg sync.WaitGroup
+
+	// Only ten transfers of 1000 plus their fee fit into the balance.
+	errs := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs <- srvc.CreateTransaction(ctx, &models.Transaction{CreditWalletID: from, DebitWalletID: to, Amount: 1000})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+			failed++
+		}
+	}
+
+	assert.Equal(t, 10, failed)
+	assert.Equal(t, 0, balance(t, srvc, from))
+	assert.Equal(t, 10000, balance(t, srvc, to))
+	assert.Equal(t, 150, balance(t, srvc, feeWalletID))
+}
+
+//nolint
+func TestGetWalletAmountDayByID(t *testing.T) {
+	srvc := newService(t, memory.NewRepository(&models.Wallet{ID: feeWalletID, Currency: "USD"}))
+	ctx := context.Background()
+
+	from := newWallet(t, srvc, 5000)
+	to := newWallet(t, srvc, 0)
+
+	for _, transaction := range []*models.Transaction{
+		{CreditWalletID: from, DebitWalletID: to, Amount: 1000},
+		{CreditWalletID: from, DebitWalletID: to, Amount: 2000},
+	} {
+		err := srvc.CreateTransaction(ctx, transaction)
+		assert.NoError(t, err)
+	}
+
+	now := time.Now().UTC()
+	week := models.Week{DateFrom: now.AddDate(0, 0, -1).Format("2006-01-02"), DateTo: now.AddDate(0, 0, 1).Format("2006-01-02")}
+
+	days, err := srvc.GetWalletAmountDayByID(ctx, from, week)
+
+	assert.NoError(t, err)
+	assert.Len(t, days, 1)
+	assert.Equal(t, 3000, days[0].Outcome)
+	assert.Equal(t, 0, days[0].Income)
+
+	days, err = srvc.GetWalletAmountWeekByID(ctx, to, week)
+
+	assert.NoError(t, err)
+	assert.Len(t, days, 1)
+	assert.Equal(t, 3000, days[0].Income)
+}
+
+//nolint
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) wallet.Repository {
+		return memory.NewRepository(&models.Wallet{ID: repotest.FeeWalletID, Currency: "USD"})
+	})
+}
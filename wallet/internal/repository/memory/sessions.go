@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, wallet.ErrUserNotFound
+	}
+
+	return &models.User{ID: user.ID, Name: user.Name, Role: user.Role}, nil
+}
+
+func (r *Repository) SetUserRole(ctx context.Context, id, role string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return wallet.ErrUserNotFound
+	}
+
+	user.Role = role
+
+	return nil
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addRefreshToken(token)
+
+	return nil
+}
+
+func (r *Repository) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.refresh[hash]
+	if !ok {
+		return nil, wallet.ErrRefreshTokenNotFound
+	}
+
+	found := *token
+
+	return &found, nil
+}
+
+func (r *Repository) RotateRefreshToken(ctx context.Context, hash string, next *models.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.refresh[hash]
+	if !ok {
+		return wallet.ErrRefreshTokenNotFound
+	}
+
+	if token.Used {
+		return wallet.ErrRefreshTokenReused
+	}
+
+	token.Used = true
+	r.addRefreshToken(next)
+
+	return nil
+}
+
+func (r *Repository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.byAccess[id]
+
+	return ok && token.Revoked, nil
+}
+
+func (r *Repository) addRefreshToken(token *models.RefreshToken) {
+	created := *token
+	r.refresh[created.Hash] = &created
+	r.byAccess[created.AccessTokenID] = &created
+}
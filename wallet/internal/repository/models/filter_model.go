@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Directions of a transaction relative to the wallet whose transactions are listed.
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+)
+
+// TransactionFilter narrows a list of transactions. Zero fields do not filter.
+type TransactionFilter struct {
+	// DateFrom and DateTo bound the transaction date, both inclusive.
+	DateFrom time.Time
+	DateTo   time.Time
+	// MinAmount and MaxAmount bound the amount in the currency of the sender, both inclusive.
+	MinAmount int
+	MaxAmount int
+	// Direction only applies to the transactions of a wallet.
+	Direction string
+	// The counterparty is the other side of the listed wallet, or either side when all transactions are listed.
+	CounterpartyWalletID string
+	CounterpartyUserID   string
+	Type                 *int
+	FeeWalletID          string
+	// UserID limits the list to the transactions with a wallet of the user on either side.
+	UserID string
+}
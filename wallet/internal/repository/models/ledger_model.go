@@ -0,0 +1,14 @@
+package models
+
+// LedgerEntry is one posting of the double-entry journal. The postings written for a
+// transaction or a new wallet sum to zero per currency.
+type LedgerEntry struct {
+	ID            string `json:"id" bson:"_id"`
+	TransactionID string `json:"transactionId,omitempty" bson:"transaction_id,omitempty"`
+	Account       string `json:"account" bson:"account"`
+	Amount        int    `json:"amount" bson:"amount"`
+	Currency      string `json:"currency" bson:"currency"`
+	Date          string `json:"date" bson:"date"`
+	// Balance is the running balance of the account after this posting. It is computed on read.
+	Balance int `json:"balance" bson:"-"`
+}
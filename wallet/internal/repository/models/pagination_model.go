@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Pagination selects a page of a list. A Cursor continues right after the last item of the
+// previous page and is used instead of Offset.
+type Pagination struct {
+	Offset int
+	Limit  int
+	Cursor string
+}
+
+// PageInfo describes the page that was returned. NextCursor is empty on the last page.
+type PageInfo struct {
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// EncodeCursor packs the sort key of the last item of a page into an opaque cursor.
+func EncodeCursor(key ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(key, "\n")))
+}
+
+// DecodeCursor unpacks a cursor made by EncodeCursor from a sort key of n values.
+// It reports false when the cursor is malformed.
+func DecodeCursor(cursor string, n int) ([]string, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false
+	}
+
+	key := strings.Split(string(raw), "\n")
+	if len(key) != n {
+		return nil, false
+	}
+
+	return key, true
+}
@@ -0,0 +1,8 @@
+package models
+
+// Rate is the price of one unit of From expressed in To.
+type Rate struct {
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Rate float64 `json:"rate"`
+}
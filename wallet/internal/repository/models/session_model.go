@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RefreshToken renews the access token it was issued with. Only the hash of the token is stored.
+// Every renewal marks the token used and issues a new one of the same family.
+type RefreshToken struct {
+	Hash          string    `bson:"_id"`
+	FamilyID      string    `bson:"family_id"`
+	UserID        string    `bson:"user_id"`
+	AccessTokenID string    `bson:"access_token_id"`
+	ExpiresAt     time.Time `bson:"expires_at"`
+	Used          bool      `bson:"used"`
+	Revoked       bool      `bson:"revoked"`
+}
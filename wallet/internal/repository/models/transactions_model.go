@@ -1,14 +1,35 @@
 package models
 
 type Transaction struct {
-	ID             string `json:"id"`
-	CreditWalletID string `validate:"required" json:"creditWalletId"`
-	DebitWalletID  string `validate:"required" json:"debitWalletId"`
-	Amount         int    `validate:"required" json:"amount"`
-	Type           int    `json:"type"`
-	FeeAmount      int    `json:"feeAmount"`
-	FeeWalletID    string `json:"feeWalletId"`
-	CreditUserID   string `json:"creditUserId"`
-	DebitUserID    string `json:"debitUserId"`
-	Date           string `json:"date"`
+	ID              string  `json:"id"`
+	CreditWalletID  string  `validate:"required" json:"creditWalletId"`
+	DebitWalletID   string  `validate:"required" json:"debitWalletId"`
+	Amount          int     `validate:"required,gt=0" json:"amount"`
+	Currency        string  `json:"currency"`
+	DebitAmount     int     `json:"debitAmount"`
+	DebitCurrency   string  `json:"debitCurrency"`
+	Rate            float64 `json:"rate"`
+	Type            int     `json:"type"`
+	FeeAmount       int     `json:"feeAmount"`
+	FeeWalletID     string  `json:"feeWalletId"`
+	FeeWalletAmount int     `json:"feeWalletAmount"`
+	FeeCurrency     string  `json:"feeCurrency"`
+	CreditUserID    string  `json:"creditUserId"`
+	DebitUserID     string  `json:"debitUserId"`
+	Date            string  `json:"date"`
+	IdempotencyKey  string  `validate:"omitempty,max=255" json:"idempotencyKey,omitempty" bson:"idempotency_key,omitempty"`
+	// OriginalTransactionID links a reversal to the transaction it compensates.
+	OriginalTransactionID string `json:"originalTransactionId,omitempty" bson:"original_transaction_id,omitempty"`
+	// RefundedAmount and RefundedFeeAmount sum the reversals of this transaction in its own currency.
+	RefundedAmount    int `json:"refundedAmount" bson:"refunded_amount"`
+	RefundedFeeAmount int `json:"refundedFeeAmount" bson:"refunded_fee_amount"`
+	// RefundedFeeWalletAmount sums what the fee wallet gave back, in the currency of the fee wallet.
+	RefundedFeeWalletAmount int `json:"refundedFeeWalletAmount" bson:"refunded_fee_wallet_amount"`
+}
+
+// Reversal asks to refund a transaction, fully when Amount is zero.
+type Reversal struct {
+	Amount         int    `validate:"gte=0" json:"amount"`
+	RefundFee      bool   `json:"refundFee"`
+	IdempotencyKey string `validate:"omitempty,max=255" json:"-"`
 }
@@ -1,7 +1,32 @@
 package models
 
+// Roles of users. Support staff can read every wallet and transaction and reverse transfers,
+// admins can also manage users.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
-	ID    string  `json:"id" bson:"_id"`
-	Name  string  `validate:"required" json:"name"`
-	Token *string `json:"token" bson:"token"`
+	ID           string `json:"id" bson:"_id"`
+	Name         string `validate:"required" json:"name"`
+	PasswordHash string `json:"-" bson:"password_hash,omitempty"`
+	Role         string `json:"role" bson:"role"`
+}
+
+// Credentials are the name and password a user registers and logs in with.
+type Credentials struct {
+	Name     string `validate:"required,max=64" json:"name"`
+	Password string `validate:"required" json:"password"`
+}
+
+// RoleChange is the new role of a user.
+type RoleChange struct {
+	Role string `validate:"required,oneof=user support admin" json:"role"`
+}
+
+// ValidRole reports whether role is one of the roles of users.
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleSupport || role == RoleAdmin
 }
@@ -1,7 +1,8 @@
 package models
 
 type Wallet struct {
-	ID      string `json:"id" bson:"_id"`
-	Balance int    `validate:"required" json:"balance" bson:"balance"`
-	UserID  string `validate:"required" json:"userId" bson:"user_id"`
+	ID       string `json:"id" bson:"_id"`
+	Balance  int    `validate:"required" json:"balance" bson:"balance"`
+	UserID   string `validate:"required" json:"userId" bson:"user_id"`
+	Currency string `validate:"omitempty,iso4217" json:"currency" bson:"currency"`
 }
@@ -0,0 +1,130 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dateLayout is RFC 3339 in UTC with every fraction digit kept. The dates are stored as strings
+// of the same width, so comparing them as strings orders them by time whatever the zone of the server.
+const dateLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// legacyDateLayout is how time.Time.String wrote the dates before, in the zone of the server.
+const legacyDateLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// storedDate matches the dates written with dateLayout.
+var storedDate = primitive.Regex{Pattern: `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{9}Z$`}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
+func parseDate(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Error from db")
+	}
+
+	return t, nil
+}
+
+// parseLegacyDate reads a date written by time.Time.String, dropping the monotonic clock reading.
+// Dates already in RFC 3339 are read as well.
+func parseLegacyDate(value string) (time.Time, error) {
+	if i := strings.Index(value, " m="); i != -1 {
+		value = value[:i]
+	}
+
+	t, err := time.Parse(legacyDateLayout, value)
+	if err != nil {
+		return parseDate(value)
+	}
+
+	return t, nil
+}
+
+// rewriteDates replaces the date of every document of the collection matching filter by convert.
+func rewriteDates(ctx context.Context, db *mongo.Database, collection string, filter bson.M,
+	convert func(string) (string, error)) error {
+	documents := db.Collection(collection)
+
+	cur, err := documents.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var document struct {
+			ID   interface{} `bson:"_id"`
+			Date string      `bson:"date"`
+		}
+
+		err = cur.Decode(&document)
+		if err != nil {
+			return err
+		}
+
+		date, err := convert(document.Date)
+		if err != nil {
+			return errors.Wrapf(err, "%s %v", collection, document.ID)
+		}
+
+		// Only the date changes, documents that no longer pass the validators keep the rest as it is.
+		_, err = documents.UpdateOne(ctx, bson.M{"_id": document.ID}, bson.M{"$set": bson.M{"date": date}},
+			options.Update().SetBypassDocumentValidation(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
+
+// storeDatesInUTC rewrites the dates of the transactions and ledger entries in dateLayout.
+func storeDatesInUTC(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"transactions", "ledger_entries"} {
+		err := rewriteDates(ctx, db, collection, bson.M{"date": bson.M{"$type": "string", "$not": storedDate}},
+			func(value string) (string, error) {
+				t, err := parseLegacyDate(value)
+				if err != nil {
+					return "", err
+				}
+
+				return formatDate(t), nil
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeDatesInLocalTime writes the dates back as time.Time.String did, in the zone of the server.
+func storeDatesInLocalTime(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"transactions", "ledger_entries"} {
+		err := rewriteDates(ctx, db, collection, bson.M{"date": storedDate},
+			func(value string) (string, error) {
+				t, err := parseDate(value)
+				if err != nil {
+					return "", err
+				}
+
+				return t.Local().Format(legacyDateLayout), nil
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
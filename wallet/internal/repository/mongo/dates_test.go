@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//nolint
+func TestFormatDateSortsByTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// Around the end of summer time the wall clock of Berlin runs the same hour twice.
+	base := time.Date(2022, 10, 30, 0, 30, 0, 0, time.UTC)
+	instants := []time.Time{
+		base.Add(90 * time.Minute).In(berlin),
+		base.In(berlin),
+		base.Add(time.Hour + 5*time.Nanosecond).In(berlin),
+		base.Add(time.Hour).In(berlin),
+	}
+
+	dates := make([]string, 0, len(instants))
+	for _, instant := range instants {
+		dates = append(dates, formatDate(instant))
+	}
+
+	sort.Strings(dates)
+	assert.Equal(t, []string{
+		"2022-10-30T00:30:00.000000000Z",
+		"2022-10-30T01:30:00.000000000Z",
+		"2022-10-30T01:30:00.000000005Z",
+		"2022-10-30T02:00:00.000000000Z",
+	}, dates)
+
+	parsed, err := parseDate(dates[2])
+	require.NoError(t, err)
+	assert.True(t, parsed.Equal(base.Add(time.Hour+5*time.Nanosecond)))
+}
+
+//nolint
+func TestParseLegacyDate(t *testing.T) {
+	for _, value := range []string{
+		"2022-10-30 02:30:00.5 +0100 CET m=+12.000000001",
+		"2022-10-30 02:30:00.5 +0100 CET",
+		"2022-10-30T01:30:00.5Z",
+	} {
+		parsed, err := parseLegacyDate(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, "2022-10-30T01:30:00.500000000Z", formatDate(parsed), value)
+	}
+
+	_, err := parseLegacyDate("yesterday")
+	assert.Error(t, err)
+}
+
+//nolint
+func TestTransactionFilterDatesInUTC(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	filter := transactionFilter("", models.TransactionFilter{
+		DateFrom: time.Date(2022, 10, 30, 3, 30, 0, 0, berlin),
+		DateTo:   time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC),
+	})
+
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"date": bson.M{"$gte": "2022-10-30T02:30:00.000000000Z"}},
+		bson.M{"date": bson.M{"$lte": "2022-10-31T00:00:00.000000000Z"}},
+	}}, filter)
+}
@@ -0,0 +1,504 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// lockID is the document of the schema_lock collection held while migrating.
+	lockID = "migrate"
+	// lockTTL frees the lock of a migrator that died without releasing it.
+	lockTTL = 10 * time.Minute
+	// lockRetry is how often a waiting migrator tries to take the lock.
+	lockRetry = 500 * time.Millisecond
+
+	// systemUserID owns the fee wallet feeWalletID. Both have the ids the Postgres backend
+	// creates them with.
+	systemUserID = "66aeb414-335a-4d1d-9dd9-6622b9c179a9"
+	feeWalletID  = "85aa7525-4fdb-4436-a600-66ffc55e0f65"
+)
+
+// migration changes the wallet database. Every step must be safe to run again, as the
+// step and its record in schema_migrations are not written atomically.
+type migration struct {
+	migrate.Migration
+	up   func(ctx context.Context, db *mongo.Database) error
+	down func(ctx context.Context, db *mongo.Database) error
+}
+
+var migrations = []migration{
+	{migrate.Migration{Version: 1, Name: "indexes"}, createIndexes, dropIndexes},
+	{migrate.Migration{Version: 2, Name: "validators"}, addValidators, removeValidators},
+	{migrate.Migration{Version: 3, Name: "user_passwords"}, addUserPasswords, removeUserPasswords},
+	{migrate.Migration{Version: 4, Name: "refresh_tokens"}, createRefreshTokenIndexes, dropRefreshTokenIndexes},
+	{migrate.Migration{Version: 5, Name: "user_roles"}, addUserRoles, removeUserRoles},
+	{migrate.Migration{Version: 6, Name: "fee_wallet"}, createFeeWallet, keepFeeWallet},
+	{migrate.Migration{Version: 7, Name: "idempotency_key_scope"}, scopeIdempotencyKeys, unscopeIdempotencyKeys},
+	{migrate.Migration{Version: 8, Name: "positive_amount"}, requirePositiveAmounts, allowZeroAmounts},
+	{migrate.Migration{Version: 9, Name: "utc_dates"}, storeDatesInUTC, storeDatesInLocalTime},
+	{migrate.Migration{Version: 10, Name: "transaction_date_order"}, createDateOrderIndexes, dropDateOrderIndexes},
+}
+
+// Migrator applies the migrations of the wallet database and records them in the
+// schema_migrations collection. Mongo has no advisory locks, so a lock document stands in.
+type Migrator struct {
+	db    *mongo.Database
+	owner string
+}
+
+func NewMigrator(client *mongo.Client) *Migrator {
+	return &Migrator{db: client.Database("wallet"), owner: primitive.NewObjectID().Hex()}
+}
+
+func (m *Migrator) Migrations() []migrate.Migration {
+	list := make([]migrate.Migration, 0, len(migrations))
+	for _, mg := range migrations {
+		list = append(list, mg.Migration)
+	}
+
+	return list
+}
+
+// Lock takes the lock document unless another migrator holds an unexpired one, and waits otherwise.
+func (m *Migrator) Lock(ctx context.Context) (func(), error) {
+	locks := m.db.Collection("schema_lock")
+
+	for {
+		now := time.Now()
+
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": lockID, "until": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": m.owner, "until": now.Add(lockTTL)}},
+			options.Update().SetUpsert(true))
+		if err == nil {
+			break
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, errors.Wrap(err, "Error from db")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+
+	return func() {
+		locks.DeleteOne(context.Background(), bson.M{"_id": lockID, "owner": m.owner})
+	}, nil
+}
+
+func (m *Migrator) Applied(ctx context.Context) (map[int]bool, error) {
+	cursor, err := m.db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	var records []struct {
+		Version int `bson:"_id"`
+	}
+
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	applied := make(map[int]bool)
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) Up(ctx context.Context, mg migrate.Migration) error {
+	err := find(mg).up(ctx, m.db)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	_, err = m.db.Collection("schema_migrations").InsertOne(ctx,
+		bson.M{"_id": mg.Version, "name": mg.Name, "applied_at": time.Now()})
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func (m *Migrator) Down(ctx context.Context, mg migrate.Migration) error {
+	err := find(mg).down(ctx, m.db)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	_, err = m.db.Collection("schema_migrations").DeleteOne(ctx, bson.M{"_id": mg.Version})
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func find(mg migrate.Migration) migration {
+	for _, candidate := range migrations {
+		if candidate.Version == mg.Version {
+			return candidate
+		}
+	}
+
+	return migration{}
+}
+
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("transactions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"idempotency_key": 1},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.M{"id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "creditwalletid", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "debitwalletid", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.M{"credituserid": 1}},
+		{Keys: bson.M{"debituserid": 1}},
+		{Keys: bson.M{"date": 1}},
+		{Keys: bson.M{"amount": 1}},
+		{Keys: bson.M{"feewalletid": 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("ledger_entries").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "account", Value: 1}, {Key: "_id", Value: 1}},
+	})
+
+	return err
+}
+
+// dropIndexes drops the indexes of createIndexes by their default names.
+func dropIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]string{
+		"transactions": {
+			"idempotency_key_1", "id_1", "creditwalletid_1_id_1", "debitwalletid_1_id_1", "credituserid_1",
+			"debituserid_1", "date_1", "amount_1", "feewalletid_1",
+		},
+		"ledger_entries": {"account_1__id_1"},
+	}
+
+	for collection, names := range indexes {
+		for _, name := range names {
+			_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// integer matches both sizes the driver stores a Go int with.
+var integer = bson.M{"bsonType": bson.A{"int", "long"}}
+
+func nonNegative() bson.M {
+	return bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0}
+}
+
+func positive() bson.M {
+	return bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1}
+}
+
+// transactionsValidator describes the transactions, which move an amount matching the schema amount.
+func transactionsValidator(amount bson.M) bson.M {
+	return bson.M{"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": bson.A{"id", "creditwalletid", "debitwalletid", "amount", "debitamount", "date"},
+		"properties": bson.M{
+			"amount":                     amount,
+			"debitamount":                nonNegative(),
+			"feeamount":                  nonNegative(),
+			"refunded_amount":            nonNegative(),
+			"refunded_fee_amount":        nonNegative(),
+			"refunded_fee_wallet_amount": nonNegative(),
+			"type":                       integer,
+		},
+	}}
+}
+
+var validators = map[string]bson.M{
+	"wallets": {"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": bson.A{"_id", "balance", "user_id", "currency"},
+		"properties": bson.M{
+			"balance":  nonNegative(),
+			"user_id":  bson.M{"bsonType": "string"},
+			"currency": bson.M{"bsonType": "string", "minLength": 3, "maxLength": 3},
+		},
+	}},
+	"transactions": transactionsValidator(positive()),
+	"ledger_entries": {"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": bson.A{"account", "amount", "currency", "date"},
+		"properties": bson.M{
+			"account": bson.M{"bsonType": "string"},
+			"amount":  integer,
+		},
+	}},
+}
+
+// addValidators backfills the fields the validators require first, as Mongo rejects any later
+// update of a document that does not pass them.
+func addValidators(ctx context.Context, db *mongo.Database) error {
+	err := backfillCurrencies(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for collection, validator := range validators {
+		err := setValidator(ctx, db, collection, validator)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillCurrencies gives the wallets and transactions written before currencies the values
+// the Postgres backend defaults them to: USD, a rate of 1 and debit and fee wallet amounts equal
+// to the amount and the fee.
+func backfillCurrencies(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("wallets").UpdateMany(ctx,
+		bson.M{"currency": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"currency": "USD"}})
+	if err != nil {
+		return err
+	}
+
+	transactions := db.Collection("transactions")
+
+	for field, value := range map[string]interface{}{
+		"currency":        "USD",
+		"debitcurrency":   "USD",
+		"rate":            1.0,
+		"debitamount":     "$amount",
+		"feewalletamount": "$feeamount",
+	} {
+		_, err = transactions.UpdateMany(ctx, bson.M{field: bson.M{"$exists": false}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{field: value}}}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeValidators(ctx context.Context, db *mongo.Database) error {
+	for collection := range validators {
+		err := setValidator(ctx, db, collection, bson.M{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setValidator replaces the validator of the collection, which is created when missing.
+func setValidator(ctx context.Context, db *mongo.Database, collection string, validator bson.M) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collection})
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return db.CreateCollection(ctx, collection, options.CreateCollection().SetValidator(validator))
+	}
+
+	return db.RunCommand(ctx, bson.D{{Key: "collMod", Value: collection}, {Key: "validator", Value: validator}}).Err()
+}
+
+// addUserPasswords drops the stored bearer tokens and makes names unique among the users with a password.
+func addUserPasswords(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+
+	_, err := users.UpdateMany(ctx, bson.M{"token": bson.M{"$exists": true}}, bson.M{"$unset": bson.M{"token": ""}})
+	if err != nil {
+		return err
+	}
+
+	_, err = users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"name": 1},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"password_hash": bson.M{"$exists": true}}),
+	})
+
+	return err
+}
+
+// removeUserPasswords drops the unique index. The tokens are gone for good.
+func removeUserPasswords(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().DropOne(ctx, "name_1")
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// createRefreshTokenIndexes indexes the lookups of refresh tokens. Expired tokens are deleted by
+// Mongo, the access tokens issued with them have expired long before.
+func createRefreshTokenIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"family_id": 1}},
+		{
+			Keys:    bson.M{"access_token_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
+
+func dropRefreshTokenIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{"family_id_1", "access_token_id_1", "expires_at_1"} {
+		_, err := db.Collection("refresh_tokens").Indexes().DropOne(ctx, name)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addUserRoles gives the user role to the users created before roles.
+func addUserRoles(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"role": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"role": "user"}})
+
+	return err
+}
+
+func removeUserRoles(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"role": ""}})
+
+	return err
+}
+
+// createFeeWallet creates the system user and the USD wallet the fees are paid to, unless they exist.
+func createFeeWallet(ctx context.Context, db *mongo.Database) error {
+	upsert := options.Update().SetUpsert(true)
+
+	_, err := db.Collection("users").UpdateOne(ctx, bson.M{"_id": systemUserID},
+		bson.M{"$setOnInsert": bson.M{"name": "", "role": "user"}}, upsert)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("wallets").UpdateOne(ctx, bson.M{"_id": feeWalletID},
+		bson.M{"$setOnInsert": bson.M{"balance": 0, "user_id": systemUserID, "currency": "USD"}}, upsert)
+
+	return err
+}
+
+// keepFeeWallet leaves the fee wallet in place, as it holds the fees collected.
+func keepFeeWallet(context.Context, *mongo.Database) error {
+	return nil
+}
+
+// scopeIdempotencyKeys makes idempotency keys unique per sending wallet instead of across all
+// transactions, as unrelated clients may pick the same key.
+func scopeIdempotencyKeys(ctx context.Context, db *mongo.Database) error {
+	indexes := db.Collection("transactions").Indexes()
+
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "creditwalletid", Value: 1}, {Key: "idempotency_key", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = indexes.DropOne(ctx, "idempotency_key_1")
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func unscopeIdempotencyKeys(ctx context.Context, db *mongo.Database) error {
+	indexes := db.Collection("transactions").Indexes()
+
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = indexes.DropOne(ctx, "creditwalletid_1_idempotency_key_1")
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound")
+}
+
+// requirePositiveAmounts replaces the validator of the transactions, which let a zero amount pass.
+// Mongo checks the documents written before only when they are updated.
+func requirePositiveAmounts(ctx context.Context, db *mongo.Database) error {
+	return setValidator(ctx, db, "transactions", validators["transactions"])
+}
+
+func allowZeroAmounts(ctx context.Context, db *mongo.Database) error {
+	return setValidator(ctx, db, "transactions", transactionsValidator(nonNegative()))
+}
+
+// createDateOrderIndexes indexes the transactions by (date, id), the order they are listed in.
+func createDateOrderIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("transactions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "creditwalletid", Value: 1}, {Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "debitwalletid", Value: 1}, {Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+	})
+
+	return err
+}
+
+func dropDateOrderIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{"date_1_id_1", "creditwalletid_1_date_1_id_1", "debitwalletid_1_date_1_id_1"} {
+		_, err := db.Collection("transactions").Indexes().DropOne(ctx, name)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,30 +2,32 @@ package mongo
 
 import (
 	"context"
-	"fmt"
-	"log"
 	"time"
 
-	paginate "github.com/gobeam/mongo-go-pagination"
 	"github.com/pkg/errors"
 	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 type Repository struct {
 	Conn *mongo.Client
 }
 
-var ctx = context.TODO()
+// transactionTimeout bounds how long a transfer keeps retrying transient transaction errors.
+const transactionTimeout = 30 * time.Second
 
 func NewRepository(conn *mongo.Client) *Repository {
 	return &Repository{Conn: conn}
 }
 
 func NewMongoDB(dsn string) (*mongo.Client, error) {
+	ctx := context.Background()
 	clientOptions := options.Client().ApplyURI(dsn)
 
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -41,251 +43,649 @@ func NewMongoDB(dsn string) (*mongo.Client, error) {
 	return client, nil
 }
 
-func (r *Repository) CreateUser(token string) error {
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
 	collection := r.Conn.Database("wallet").Collection("users")
-	user := &models.User{
-		ID:    primitive.NewObjectID().String(),
-		Token: &token,
+
+	user.ID = primitive.NewObjectID().String()
+	if user.Role == "" {
+		user.Role = models.RoleUser
 	}
 
 	_, err := collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return wallet.ErrUserExists
+	}
 
-	return err
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+// GetUserByName only finds users with a password. Users created before passwords share names freely.
+func (r *Repository) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	collection := r.Conn.Database("wallet").Collection("users")
+
+	user := new(models.User)
+
+	err := collection.FindOne(ctx, bson.M{"name": name, "password_hash": bson.M{"$exists": true}}).Decode(user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return user, nil
 }
 
-func (r *Repository) GetUsers() ([]*models.User, error) {
+func (r *Repository) GetUsers(ctx context.Context, page models.Pagination) ([]*models.User, *models.PageInfo, error) {
 	collection := r.Conn.Database("wallet").Collection("users")
 	users := make([]*models.User, 0)
 
-	cur, err := collection.Find(ctx, bson.M{})
+	info, err := r.list(ctx, collection, userKeys, bson.M{}, page, &users, userFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(users) > page.Limit {
+		users = users[:page.Limit]
+		info.NextCursor = models.EncodeCursor(users[page.Limit-1].ID)
+	}
+
+	return users, info, nil
+}
+
+func (r *Repository) StreamUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error {
+	cur, err := findPage(ctx, r.Conn.Database("wallet").Collection("users"), userKeys, bson.M{}, page, userFields)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
+	defer cur.Close(ctx)
+
 	for cur.Next(ctx) {
 		user := new(models.User)
-		err := cur.Decode(&user)
+
+		err = cur.Decode(user)
 		if err != nil {
-			log.Fatal(err)
+			return errors.Wrap(err, "Error from db")
 		}
 
-		users = append(users, user)
+		err = fn(user)
+		if err != nil {
+			return err
+		}
 	}
 
-	if err := cur.Err(); err != nil {
-		log.Fatal(err)
+	if err = cur.Err(); err != nil {
+		return errors.Wrap(err, "Error from db")
 	}
 
-	cur.Close(context.TODO())
+	return nil
+}
+
+// userFields leaves the password hash out of user lists.
+var userFields = bson.M{"password_hash": 0}
+
+// userKeys order the users by id.
+var userKeys = []string{"_id"}
+
+// transactionKeys order the transactions oldest first, as the other backends do. The id breaks ties.
+var transactionKeys = []string{"date", "id"}
+
+// list decodes into result a page of the documents matching filter, ordered by the key fields.
+// One document more than the limit is fetched to tell whether there is a next page.
+func (r *Repository) list(ctx context.Context, collection *mongo.Collection, keys []string, filter bson.M,
+	page models.Pagination, result interface{}, projection bson.M) (*models.PageInfo, error) {
+	info := new(models.PageInfo)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	info.Total = int(total)
+
+	page.Limit++
+
+	cur, err := findPage(ctx, collection, keys, filter, page, projection)
+	if err != nil {
+		return nil, err
+	}
 
-	return users, nil
+	err = cur.All(ctx, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return info, nil
 }
 
-func (r *Repository) CreateWallet(wallet *models.Wallet) error {
+// findPage opens a cursor on the documents of page matching filter, ordered by the key fields.
+// The last key is unique, a cursor holds the keys of the last document of the previous page.
+func findPage(ctx context.Context, collection *mongo.Collection, keys []string, filter bson.M,
+	page models.Pagination, projection bson.M) (*mongo.Cursor, error) {
+	if page.Cursor != "" {
+		last, ok := models.DecodeCursor(page.Cursor, len(keys))
+		if !ok {
+			return nil, wallet.ErrInvalidCursor
+		}
+
+		filter = bson.M{"$and": bson.A{filter, after(keys, last)}}
+	}
+
+	sort := bson.D{}
+	for _, key := range keys {
+		sort = append(sort, bson.E{Key: key, Value: 1})
+	}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64(page.Offset)).
+		SetLimit(int64(page.Limit))
+
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return cur, nil
+}
+
+// after matches the documents whose keys sort after the values, compared key by key.
+func after(keys, values []string) bson.M {
+	alternatives := bson.A{}
+
+	for i, key := range keys {
+		alternative := bson.M{key: bson.M{"$gt": values[i]}}
+		for j := 0; j < i; j++ {
+			alternative[keys[j]] = values[j]
+		}
+
+		alternatives = append(alternatives, alternative)
+	}
+
+	return bson.M{"$or": alternatives}
+}
+
+func (r *Repository) CreateWallet(ctx context.Context, w *models.Wallet) error {
 	collection := r.Conn.Database("wallet").Collection("wallets")
 
-	wallet.ID = primitive.NewObjectID().String()
+	w.ID = primitive.NewObjectID().String()
 
-	_, err := collection.InsertOne(ctx, wallet)
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		_, err := collection.InsertOne(sc, w)
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
 
-	return err
+		// The balance is already set on the document, only the journal needs the opening postings.
+		return r.insertEntries(sc, wallet.OpeningEntries(w))
+	})
 }
 
-func (r *Repository) GetWalletByID(id string) (*models.Wallet, error) {
+func (r *Repository) GetWalletByID(ctx context.Context, id string) (*models.Wallet, error) {
 	collection := r.Conn.Database("wallet").Collection("wallets")
 
-	wallet := new(models.Wallet)
+	w := new(models.Wallet)
+
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&w)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrWalletNotFound
+	}
 
-	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&wallet)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	return wallet, nil
+	return w, nil
 }
 
-func (r *Repository) GetWalletTransactionsByID(id string) ([]*models.Transaction, error) {
-	collection := r.Conn.Database("wallet").Collection("transactions")
+func (r *Repository) GetWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	return r.listTransactions(ctx, transactionFilter(id, filter), page)
+}
 
-	transactions := make([]*models.Transaction, 0)
+func (r *Repository) GetTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	return r.listTransactions(ctx, transactionFilter("", filter), page)
+}
 
-	cur, err := collection.Find(ctx, bson.M{})
-	if err != nil {
-		log.Fatal(err)
-	}
+// transactionFilter translates the filter into a query on indexed fields.
+// When walletID is set, only its transactions match and the counterparty is the other side.
+func transactionFilter(walletID string, filter models.TransactionFilter) bson.M {
+	conditions := bson.A{}
+
+	if walletID != "" {
+		switch filter.Direction {
+		case models.DirectionIncoming:
+			conditions = append(conditions, bson.M{"debitwalletid": walletID})
+		case models.DirectionOutgoing:
+			conditions = append(conditions, bson.M{"creditwalletid": walletID})
+		default:
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{"creditwalletid": walletID}, bson.M{"debitwalletid": walletID},
+			}})
+		}
 
-	for cur.Next(ctx) {
-		transaction := new(models.Transaction)
-		err := cur.Decode(&transaction)
-		if err != nil {
-			log.Fatal(err)
+		if filter.CounterpartyWalletID != "" {
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{"creditwalletid": walletID, "debitwalletid": filter.CounterpartyWalletID},
+				bson.M{"debitwalletid": walletID, "creditwalletid": filter.CounterpartyWalletID},
+			}})
 		}
 
-		transactions = append(transactions, transaction)
+		if filter.CounterpartyUserID != "" {
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{"creditwalletid": walletID, "debituserid": filter.CounterpartyUserID},
+				bson.M{"debitwalletid": walletID, "credituserid": filter.CounterpartyUserID},
+			}})
+		}
+	} else {
+		if filter.CounterpartyWalletID != "" {
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{"creditwalletid": filter.CounterpartyWalletID},
+				bson.M{"debitwalletid": filter.CounterpartyWalletID},
+			}})
+		}
+
+		if filter.CounterpartyUserID != "" {
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{"credituserid": filter.CounterpartyUserID},
+				bson.M{"debituserid": filter.CounterpartyUserID},
+			}})
+		}
 	}
 
-	if err := cur.Err(); err != nil {
-		log.Fatal(err)
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, bson.M{"date": bson.M{"$gte": formatDate(filter.DateFrom)}})
+	}
+
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, bson.M{"date": bson.M{"$lte": formatDate(filter.DateTo)}})
 	}
 
-	cur.Close(context.TODO())
+	if filter.MinAmount != 0 {
+		conditions = append(conditions, bson.M{"amount": bson.M{"$gte": filter.MinAmount}})
+	}
+
+	if filter.MaxAmount != 0 {
+		conditions = append(conditions, bson.M{"amount": bson.M{"$lte": filter.MaxAmount}})
+	}
+
+	if filter.Type != nil {
+		conditions = append(conditions, bson.M{"type": *filter.Type})
+	}
+
+	if filter.FeeWalletID != "" {
+		conditions = append(conditions, bson.M{"feewalletid": filter.FeeWalletID})
+	}
+
+	if filter.UserID != "" {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"credituserid": filter.UserID},
+			bson.M{"debituserid": filter.UserID},
+		}})
+	}
+
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
 
-	return transactions, nil
+	return bson.M{"$and": conditions}
 }
 
-func (r *Repository) GetTransactions() ([]*models.Transaction, error) {
+// listTransactions returns a page of the transactions matching filter, oldest first.
+func (r *Repository) listTransactions(ctx context.Context, filter bson.M,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
 	collection := r.Conn.Database("wallet").Collection("transactions")
 	transactions := make([]*models.Transaction, 0)
 
-	cur, err := collection.Find(ctx, bson.M{})
+	info, err := r.list(ctx, collection, transactionKeys, filter, page, &transactions, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
+	}
+
+	if len(transactions) > page.Limit {
+		transactions = transactions[:page.Limit]
+		last := transactions[page.Limit-1]
+		info.NextCursor = models.EncodeCursor(last.Date, last.ID)
 	}
 
+	return transactions, info, nil
+}
+
+func (r *Repository) StreamWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	return r.streamTransactions(ctx, transactionFilter(id, filter), page, fn)
+}
+
+func (r *Repository) StreamTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	return r.streamTransactions(ctx, transactionFilter("", filter), page, fn)
+}
+
+// streamTransactions calls fn for the transactions of page matching filter as the cursor reads them.
+func (r *Repository) streamTransactions(ctx context.Context, filter bson.M, page models.Pagination,
+	fn func(*models.Transaction) error) error {
+	cur, err := findPage(ctx, r.Conn.Database("wallet").Collection("transactions"), transactionKeys, filter, page, nil)
+	if err != nil {
+		return err
+	}
+
+	defer cur.Close(ctx)
+
 	for cur.Next(ctx) {
 		transaction := new(models.Transaction)
-		err := cur.Decode(&transaction)
+
+		err = cur.Decode(transaction)
 		if err != nil {
-			log.Fatal(err)
+			return errors.Wrap(err, "Error from db")
 		}
 
-		transactions = append(transactions, transaction)
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
 	}
 
-	if err := cur.Err(); err != nil {
-		log.Fatal(err)
+	if err = cur.Err(); err != nil {
+		return errors.Wrap(err, "Error from db")
 	}
 
-	cur.Close(context.TODO())
-
-	return transactions, nil
+	return nil
 }
 
-func (r *Repository) CreateTransaction(transaction *models.Transaction) error {
+func (r *Repository) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if transaction.Amount <= 0 {
+		return wallet.ErrInvalidAmount
+	}
+
 	collectionWallet := r.Conn.Database("wallet").Collection("wallets")
 	collectionTransactions := r.Conn.Database("wallet").Collection("transactions")
 
-	//var session mongo.Session
-
 	transaction.ID = primitive.NewObjectID().String()
-	t := time.Now()
-	transaction.Date = t.String()
+	transaction.Date = formatDate(time.Now())
+
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		err := fillUsers(sc, collectionWallet, transaction)
+		if err != nil {
+			return err
+		}
+
+		if transaction.OriginalTransactionID != "" {
+			err := refund(sc, collectionTransactions, transaction)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = collectionTransactions.InsertOne(sc, transaction)
+		if mongo.IsDuplicateKeyError(err) {
+			return wallet.ErrDuplicateIdempotencyKey
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
 
-	_, err := collectionWallet.UpdateOne(ctx, bson.M{"_id": transaction.CreditWalletID}, bson.D{{"$inc", bson.D{{"balance", -transaction.Amount}}}}, options.Update().SetUpsert(false))
+		return r.applyEntries(sc, collectionWallet, wallet.TransferEntries(transaction))
+	})
+}
+
+// withTransaction runs fn inside a multi-document transaction. The whole callback is
+// retried on TransientTransactionError and the commit on UnknownTransactionCommitResult
+// until ctx is done or transactionTimeout passes.
+func (r *Repository) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := r.Conn.StartSession()
 	if err != nil {
-		return err
+		return errors.Wrap(err, "Error from db")
 	}
 
-	_, err = collectionWallet.UpdateOne(ctx, bson.M{"_id": transaction.DebitWalletID}, bson.D{{"$inc", bson.D{{"balance", transaction.Amount}}}}, options.Update().SetUpsert(false))
-	if err != nil {
-		return err
+	defer session.EndSession(ctx)
+
+	tctx, cancel := context.WithTimeout(ctx, transactionTimeout)
+	defer cancel()
+
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+
+	_, err = session.WithTransaction(tctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, opts)
+
+	return err
+}
+
+// fillUsers copies the owners of both wallets onto the transaction.
+func fillUsers(sc mongo.SessionContext, collectionWallet *mongo.Collection, transaction *models.Transaction) error {
+	sides := []struct {
+		walletID string
+		userID   *string
+	}{
+		{transaction.CreditWalletID, &transaction.CreditUserID},
+		{transaction.DebitWalletID, &transaction.DebitUserID},
+	}
+
+	for _, side := range sides {
+		w := new(models.Wallet)
+
+		err := collectionWallet.FindOne(sc, bson.M{"_id": side.walletID}).Decode(w)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return wallet.ErrWalletNotFound
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
+
+		*side.userID = w.UserID
+	}
+
+	return nil
+}
+
+// refund adds the reversal to the refunded totals of the original transaction. Concurrent reversals
+// write the same document, so all but one abort and are retried against the new totals.
+func refund(sc mongo.SessionContext, collectionTransactions *mongo.Collection, reversal *models.Transaction) error {
+	original := new(models.Transaction)
+
+	err := collectionTransactions.FindOneAndUpdate(sc, bson.M{"id": reversal.OriginalTransactionID},
+		bson.M{"$inc": bson.M{
+			"refunded_amount":            reversal.DebitAmount,
+			"refunded_fee_amount":        reversal.FeeAmount,
+			"refunded_fee_wallet_amount": reversal.FeeWalletAmount,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(original)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return wallet.ErrTransactionNotFound
 	}
 
-	_, err = collectionTransactions.InsertOne(ctx, transaction)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "Error from db")
 	}
 
-	//session, err := r.Conn.StartSession()
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//defer session.EndSession(ctx)
-	//
-	//err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-	//	err = session.StartTransaction()
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	_, err = collectionWallet.UpdateOne(sc, bson.M{"_id": transaction.CreditWalletID}, bson.D{{"$inc", bson.D{{"balance", -transaction.Amount}}}}, options.Update().SetUpsert(false))
-	//	if err != nil {
-	//		session.AbortTransaction(sc)
-	//		return err
-	//	}
-	//
-	//	_, err = collectionWallet.UpdateOne(sc, bson.M{"_id": transaction.DebitWalletID}, bson.D{{"$inc", bson.D{{"balance", transaction.Amount}}}}, options.Update().SetUpsert(false))
-	//	if err != nil {
-	//		session.AbortTransaction(sc)
-	//		return err
-	//	}
-	//
-	//	_, err = collectionTransactions.InsertOne(ctx, transaction)
-	//	if err != nil {
-	//		session.AbortTransaction(sc)
-	//		return err
-	//	}
-	//
-	//	if err = session.CommitTransaction(sc); err != nil {
-	//		session.AbortTransaction(sc)
-	//		return err
-	//	}
-	//
-	//	return nil
-	//})
+	return wallet.CheckRefunds(original, reversal)
+}
+
+// applyEntries moves the cached balance of every wallet account by its posting and writes the postings
+// to the journal. A wallet is only debited when its balance covers the posting.
+func (r *Repository) applyEntries(sc mongo.SessionContext, collectionWallet *mongo.Collection, entries []*models.LedgerEntry) error {
+	for _, entry := range entries {
+		if !wallet.IsWalletAccount(entry.Account) {
+			continue
+		}
+
+		filter := bson.M{"_id": entry.Account}
+		if entry.Amount < 0 {
+			filter["balance"] = bson.M{"$gte": -entry.Amount}
+		}
+
+		res, err := collectionWallet.UpdateOne(sc, filter, bson.M{"$inc": bson.M{"balance": entry.Amount}}, options.Update().SetUpsert(false))
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
+
+		if res.MatchedCount != 0 {
+			continue
+		}
+
+		count, err := collectionWallet.CountDocuments(sc, bson.M{"_id": entry.Account})
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
+
+		if count == 0 {
+			return wallet.ErrWalletNotFound
+		}
+
+		return wallet.ErrInsufficientFunds
+	}
+
+	return r.insertEntries(sc, entries)
+}
+
+func (r *Repository) insertEntries(sc mongo.SessionContext, entries []*models.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	collectionLedger := r.Conn.Database("wallet").Collection("ledger_entries")
+
+	date := formatDate(time.Now())
+	documents := make([]interface{}, 0, len(entries))
+
+	for _, entry := range entries {
+		entry.ID = primitive.NewObjectID().String()
+		if entry.Date == "" {
+			entry.Date = date
+		}
+
+		documents = append(documents, entry)
+	}
+
+	_, err := collectionLedger.InsertMany(sc, documents)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
 
 	return nil
 }
 
-func (r *Repository) GetWalletAmountDayByID(id string, week models.Week) ([]*models.Day, error) {
-	collectionTransactions := r.Conn.Database("wallet").Collection("transactions")
+func (r *Repository) GetLedgerEntriesByWalletID(ctx context.Context, id string) ([]*models.LedgerEntry, error) {
+	collection := r.Conn.Database("wallet").Collection("ledger_entries")
 
-	//matchStage := bson.D{{"$match", bson.D{{"date", bson.D{{"$gte", week.DateFrom}, {"$lt", week.DateTo}}}}}}
-	//groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"$dateFromString", bson.D{
-	//	{"dateString", "$b"},
-	//	{"format", "%d/%m/%Y"}}}}}, {"total", bson.D{{"$sum", "$amount"}}}}}}
-	//
-	//showInfoCursor, err := collectionTransactions.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
-	//if err != nil {
-	//	panic(err)
-	//}
-	//var showsWithInfo []bson.M
-	//if err = showInfoCursor.All(ctx, &showsWithInfo); err != nil {
-	//	panic(err)
-	//}
-	//fmt.Println(showsWithInfo)
-
-	match := bson.M{"$match": bson.M{"creditwalletid": id}}
-	project := bson.M{
-		"$project": bson.M{
-			"date": "$date",
-			//"month":         bson.M{"month": "$month"},
-			"expenseAmount": "$amount",
-		},
-	}
-	group := bson.M{
-		"$group": bson.M{
-			"_id": bson.M{
-				//"month": "$month",
-				"date": "$date",
-				//"category": "$category",
-			},
-			"outcome": bson.M{"$sum": "$expenseAmount"},
-		},
-	}
-
-	days := make([]*models.Day, 0)
-
-	paginatedData, err := paginate.New(collectionTransactions).
-		Page(1).
-		Limit(20).
-		Aggregate(match, project, group)
+	cur, err := collection.Find(ctx, bson.M{"account": id}, options.Find().SetSort(bson.M{"_id": 1}))
 	if err != nil {
-		fmt.Println(err)
+		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	for _, raw := range paginatedData.Data {
-		day := new(models.Day)
-		if marshallErr := bson.Unmarshal(raw, &day); marshallErr == nil {
-			days = append(days, day)
+	defer cur.Close(ctx)
+
+	entries := make([]*models.LedgerEntry, 0)
+	balance := 0
+
+	for cur.Next(ctx) {
+		entry := new(models.LedgerEntry)
+		if err := cur.Decode(entry); err != nil {
+			return nil, errors.Wrap(err, "Error from db")
 		}
 
+		balance += entry.Amount
+		entry.Balance = balance
+
+		entries = append(entries, entry)
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return entries, nil
+}
+
+func (r *Repository) GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error) {
+	collection := r.Conn.Database("wallet").Collection("transactions")
+
+	transaction := new(models.Transaction)
+
+	err := collection.FindOne(ctx, bson.M{"id": id}).Decode(&transaction)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return transaction, nil
+}
+
+func (r *Repository) GetTransactionByIdempotencyKey(ctx context.Context, walletID, key string) (*models.Transaction, error) {
+	collection := r.Conn.Database("wallet").Collection("transactions")
+
+	transaction := new(models.Transaction)
+
+	err := collection.FindOne(ctx, bson.M{"creditwalletid": walletID, "idempotency_key": key}).Decode(&transaction)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	return days, nil
+	return transaction, nil
+}
+
+func (r *Repository) GetWalletAmountDayByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, wallet.PeriodDay)
 }
 
-func (r *Repository) GetWalletAmountWeekByID(id string, week models.Week) ([]*models.Day, error) {
-	return nil, nil
+func (r *Repository) GetWalletAmountWeekByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, wallet.PeriodWeek)
+}
+
+// aggregate groups the transactions of the wallet in Go, as the stored dates are strings
+// that the aggregation pipeline cannot truncate.
+func (r *Repository) aggregate(ctx context.Context, id string, week models.Week, period string) ([]*models.Day, error) {
+	from, to, err := wallet.ParseWeek(week)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := r.Conn.Database("wallet").Collection("transactions")
+
+	cur, err := collection.Find(ctx, transactionFilter(id, models.TransactionFilter{DateFrom: from, DateTo: to}))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	transactions := make([]*models.Transaction, 0)
+
+	err = cur.All(ctx, &transactions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	aggregation := wallet.NewAggregation(id, period)
+
+	for _, transaction := range transactions {
+		date, err := parseDate(transaction.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregation.Add(transaction, date)
+	}
+
+	return aggregation.Days(), nil
 }
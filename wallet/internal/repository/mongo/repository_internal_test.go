@@ -0,0 +1,20 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//nolint
+func TestAfter(t *testing.T) {
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$gt": "u1"}},
+	}}, after(userKeys, []string{"u1"}))
+
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"date": bson.M{"$gt": "2022-10-30T01:30:00.000000000Z"}},
+		bson.M{"date": "2022-10-30T01:30:00.000000000Z", "id": bson.M{"$gt": "t1"}},
+	}}, after(transactionKeys, []string{"2022-10-30T01:30:00.000000000Z", "t1"}))
+}
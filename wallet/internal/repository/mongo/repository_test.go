@@ -0,0 +1,138 @@
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/migrate"
+	"github.com/workshops/wallet/internal/repository/mongo"
+	"github.com/workshops/wallet/internal/repository/repotest"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const systemUserID = "66aeb414-335a-4d1d-9dd9-6622b9c179a9"
+
+// TestConformance runs against a replica set, needed for transactions. It migrates the wallet
+// database, then empties its collections before every check but for the fee wallet, which the
+// migrations create.
+//nolint
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("WALLET_TEST_MONGO_DSN")
+	if dsn == "" {
+		t.Skip("WALLET_TEST_MONGO_DSN is not set")
+	}
+
+	client, err := mongo.NewMongoDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	err = migrate.To(context.Background(), mongo.NewMigrator(client), migrate.Latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fee, err := mongo.NewRepository(client).GetWalletByID(context.Background(), repotest.FeeWalletID)
+	if err != nil {
+		t.Fatal("the migrations create the fee wallet: ", err)
+	}
+
+	assert.Equal(t, systemUserID, fee.UserID)
+	assert.Equal(t, "USD", fee.Currency)
+
+	repotest.Run(t, func(t *testing.T) wallet.Repository {
+		ctx := context.Background()
+		db := client.Database("wallet")
+
+		// The system user and the fee wallet made by the migrations stay, with the fees taken away.
+		filters := map[string]bson.M{
+			"users":          {"_id": bson.M{"$ne": systemUserID}},
+			"wallets":        {"_id": bson.M{"$ne": repotest.FeeWalletID}},
+			"transactions":   {},
+			"ledger_entries": {},
+			"refresh_tokens": {},
+		}
+
+		for name, filter := range filters {
+			_, err := db.Collection(name).DeleteMany(ctx, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err := db.Collection("wallets").UpdateOne(ctx, bson.M{"_id": repotest.FeeWalletID}, bson.M{"$set": bson.M{"balance": 0}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return mongo.NewRepository(client)
+	})
+}
+
+// TestMigrateLegacyDocuments checks that documents written before currencies pass the
+// validators once migrated, so they can still be updated.
+//nolint
+func TestMigrateLegacyDocuments(t *testing.T) {
+	dsn := os.Getenv("WALLET_TEST_MONGO_DSN")
+	if dsn == "" {
+		t.Skip("WALLET_TEST_MONGO_DSN is not set")
+	}
+
+	client, err := mongo.NewMongoDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	ctx := context.Background()
+	db := client.Database("wallet")
+	migrator := mongo.NewMigrator(client)
+
+	err = migrate.To(ctx, migrator, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Collection("wallets").InsertOne(ctx, bson.M{"_id": "legacy-wallet", "balance": 100, "user_id": systemUserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Collection("transactions").InsertOne(ctx, bson.M{
+		"id": "legacy-transaction", "creditwalletid": "legacy-wallet", "debitwalletid": repotest.FeeWalletID,
+		"amount": 10, "feeamount": 1, "date": "2022-01-01T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Collection("wallets").DeleteOne(ctx, bson.M{"_id": "legacy-wallet"})
+		db.Collection("transactions").DeleteOne(ctx, bson.M{"id": "legacy-transaction"})
+	})
+
+	err = migrate.To(ctx, migrator, migrate.Latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Collection("wallets").UpdateOne(ctx, bson.M{"_id": "legacy-wallet"}, bson.M{"$inc": bson.M{"balance": -10}})
+	assert.NoError(t, err)
+
+	_, err = db.Collection("transactions").UpdateOne(ctx, bson.M{"id": "legacy-transaction"},
+		bson.M{"$inc": bson.M{"refunded_amount": 5}})
+	assert.NoError(t, err)
+
+	var transaction bson.M
+	assert.NoError(t, db.Collection("transactions").FindOne(ctx, bson.M{"id": "legacy-transaction"}).Decode(&transaction))
+	assert.EqualValues(t, 10, transaction["debitamount"])
+	assert.EqualValues(t, 1, transaction["feewalletamount"])
+	assert.Equal(t, "USD", transaction["currency"])
+	assert.Equal(t, "2022-01-01T00:00:00.000000000Z", transaction["date"])
+}
@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	collection := r.Conn.Database("wallet").Collection("users")
+
+	user := new(models.User)
+
+	err := collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(userFields)).Decode(user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return user, nil
+}
+
+func (r *Repository) SetUserRole(ctx context.Context, id, role string) error {
+	collection := r.Conn.Database("wallet").Collection("users")
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	if result.MatchedCount == 0 {
+		return wallet.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	_, err := r.Conn.Database("wallet").Collection("refresh_tokens").InsertOne(ctx, token)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func (r *Repository) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token := new(models.RefreshToken)
+
+	err := r.Conn.Database("wallet").Collection("refresh_tokens").FindOne(ctx, bson.M{"_id": hash}).Decode(token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wallet.ErrRefreshTokenNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken only marks the token used while it is unused, so of two concurrent
+// renewals with the same token one fails as a reuse.
+func (r *Repository) RotateRefreshToken(ctx context.Context, hash string, next *models.RefreshToken) error {
+	collection := r.Conn.Database("wallet").Collection("refresh_tokens")
+
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := collection.UpdateOne(sc, bson.M{"_id": hash, "used": false}, bson.M{"$set": bson.M{"used": true}})
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
+
+		if res.MatchedCount == 0 {
+			return wallet.ErrRefreshTokenReused
+		}
+
+		_, err = collection.InsertOne(sc, next)
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.Conn.Database("wallet").Collection("refresh_tokens").UpdateMany(ctx,
+		bson.M{"family_id": familyID}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func (r *Repository) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	token := new(models.RefreshToken)
+
+	err := r.Conn.Database("wallet").Collection("refresh_tokens").FindOne(ctx, bson.M{"access_token_id": id}).Decode(token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, "Error from db")
+	}
+
+	return token.Revoked, nil
+}
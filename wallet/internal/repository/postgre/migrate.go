@@ -0,0 +1,180 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/migrate"
+)
+
+// migrationLock is the key of the advisory lock held while migrating.
+const migrationLock = 4_212_032_001
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrator applies SQL migrations and records them in the schema_migrations table.
+// Each migration runs in its own transaction on the connection that holds the lock.
+type Migrator struct {
+	db         *sql.DB
+	conn       *sql.Conn
+	migrations []migrate.Migration
+	up         map[int]string
+	down       map[int]string
+}
+
+// NewMigrator reads the NNNNNN_name.up.sql and NNNNNN_name.down.sql files at the root of fsys.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "read migrations")
+	}
+
+	m := &Migrator{db: db, up: make(map[int]string), down: make(map[int]string)}
+	names := make(map[int]string)
+
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, errors.Errorf("migration %s has an invalid version", entry.Name())
+		}
+
+		if name, ok := names[version]; ok && name != match[2] {
+			return nil, errors.Errorf("migrations %s and %s share version %d", name, match[2], version)
+		}
+
+		names[version] = match[2]
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "read migrations")
+		}
+
+		if match[3] == "up" {
+			m.up[version] = string(script)
+		} else {
+			m.down[version] = string(script)
+		}
+	}
+
+	for version, name := range names {
+		if _, ok := m.up[version]; !ok {
+			return nil, errors.Errorf("migration %d_%s has no up file", version, name)
+		}
+
+		m.migrations = append(m.migrations, migrate.Migration{Version: version, Name: name})
+	}
+
+	return m, nil
+}
+
+func (m *Migrator) Migrations() []migrate.Migration {
+	return m.migrations
+}
+
+// Lock takes the advisory lock on a dedicated connection, which later migrations run on.
+func (m *Migrator) Lock(ctx context.Context) (func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	m.conn = conn
+
+	return func() {
+		m.conn = nil
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+		conn.Close()
+	}, nil
+}
+
+func (m *Migrator) Applied(ctx context.Context) (map[int]bool, error) {
+	_, err := m.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations "+
+		"(version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamp NOT NULL DEFAULT now())")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+
+	for rows.Next() {
+		var version int
+
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error from db")
+		}
+
+		applied[version] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) Up(ctx context.Context, migration migrate.Migration) error {
+	return m.run(ctx, m.up[migration.Version],
+		"INSERT INTO schema_migrations (version, name) VALUES ($1,$2)", migration.Version, migration.Name)
+}
+
+func (m *Migrator) Down(ctx context.Context, migration migrate.Migration) error {
+	script, ok := m.down[migration.Version]
+	if !ok {
+		return migrate.ErrIrreversible
+	}
+
+	return m.run(ctx, script, "DELETE FROM schema_migrations WHERE version=$1", migration.Version)
+}
+
+// run executes the script and the bookkeeping query in one transaction.
+func (m *Migrator) run(ctx context.Context, script, record string, args ...interface{}) error {
+	if m.conn == nil {
+		return errors.New("migrator is not locked")
+	}
+
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package postgre_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/migrate"
+	"github.com/workshops/wallet/internal/repository/postgre"
+	"github.com/workshops/wallet/migrations"
+)
+
+//nolint
+func TestEmbeddedMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+
+	migrator, err := postgre.NewMigrator(db, migrations.Postgres)
+
+	assert.NoError(t, err)
+	assert.Len(t, migrator.Migrations(), 14)
+}
+
+//nolint
+func TestMigratorMissingUp(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+
+	_, err = postgre.NewMigrator(db, fstest.MapFS{"000001_init.down.sql": {Data: []byte("drop table t;")}})
+
+	assert.Error(t, err)
+}
+
+//nolint
+func TestMigratorUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+
+	migrator, err := postgre.NewMigrator(db, fstest.MapFS{
+		"000001_init.up.sql":     {Data: []byte("create table t (id int);")},
+		"000001_init.down.sql":   {Data: []byte("drop table t;")},
+		"000002_column.up.sql":   {Data: []byte("alter table t add column name text;")},
+		"000002_column.down.sql": {Data: []byte("alter table t drop column name;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("alter table t add column name text;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1,$2)")).
+		WithArgs(2, "column").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = migrate.To(context.Background(), migrator, migrate.Latest)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestMigratorFailedRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollback error
+		message  string
+	}{
+		{"connection lost", errors.New("connection reset"), "unable to abort: connection reset"},
+		// A canceled context rolls the transaction back before rollback is called.
+		{"already rolled back", sql.ErrTxDone, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Unable to connect")
+			}
+			defer db.Close()
+
+			migrator, err := postgre.NewMigrator(db, fstest.MapFS{
+				"000001_init.up.sql":   {Data: []byte("create table t (id int);")},
+				"000001_init.down.sql": {Data: []byte("drop table t;")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(sqlmock.NewRows([]string{"version"}))
+			mock.ExpectBegin()
+			mock.ExpectExec("create table t").WillReturnError(assert.AnError)
+			mock.ExpectRollback().WillReturnError(tt.rollback)
+			mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			err = migrate.To(context.Background(), migrator, migrate.Latest)
+
+			assert.ErrorIs(t, err, assert.AnError)
+			if tt.message != "" {
+				assert.Contains(t, err.Error(), tt.message)
+			} else {
+				assert.NotContains(t, err.Error(), "unable to abort")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,12 +3,29 @@ package postgre
 import (
 	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+const (
+	transactionColumns = "id,credit_wallet_id,debit_wallet_id,amount,currency,debit_amount,debit_currency,rate," +
+		"type,fee_amount,fee_wallet_id,fee_wallet_amount,fee_currency,credit_user_id,debit_user_id,date,idempotency_key," +
+		"original_transaction_id,refunded_amount,refunded_fee_amount,refunded_fee_wallet_amount"
+
+	uniqueViolation          = "23505"
+	checkViolation           = "23514"
+	invalidTextRepresention  = "22P02"
+	invalidDatetimeFormat    = "22007"
+	idempotencyKeyConstraint = "transactions_credit_wallet_id_idempotency_key_uindex"
+	balanceConstraint        = "wallets_balance_check"
+	amountConstraint         = "transactions_amount_check"
+	userNameConstraint       = "users_name_uindex"
 )
 
 type Repository struct {
@@ -29,9 +46,17 @@ func NewPostgresDB(dsn string) (*sql.DB, error) {
 	return conn, nil
 }
 
-func (r *Repository) CreateUser(token string) error {
-	q := "INSERT INTO users (token) VALUES ($1)"
-	_, err := r.Conn.Exec(q, token)
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	q := "INSERT INTO users (name, password_hash, role) VALUES ($1,$2,$3) RETURNING id"
+	err := r.Conn.QueryRowContext(ctx, q, user.Name, nullString(user.PasswordHash), user.Role).Scan(&user.ID)
+
+	if isUniqueViolation(err, userNameConstraint) {
+		return wallet.ErrUserExists
+	}
 
 	if err != nil {
 		return errors.Wrap(err, "Error from db")
@@ -40,38 +65,137 @@ func (r *Repository) CreateUser(token string) error {
 	return nil
 }
 
-func (r *Repository) GetUsers() ([]*models.User, error) {
-	rows, err := r.Conn.Query("SELECT * FROM users")
+// GetUserByName only finds users with a password. Users created before passwords share names freely.
+func (r *Repository) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	q := "SELECT id,name,password_hash,role FROM users WHERE name=$1 AND password_hash IS NOT NULL"
+	user := new(models.User)
+	err := r.Conn.QueryRowContext(ctx, q, name).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, wallet.ErrUserNotFound
+	}
+
 	if err != nil {
 		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	defer rows.Close()
+	return user, nil
+}
+
+func (r *Repository) GetUsers(ctx context.Context, page models.Pagination) ([]*models.User, *models.PageInfo, error) {
+	info := new(models.PageInfo)
+
+	err := r.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&info.Total)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error from db")
+	}
 
 	users := make([]*models.User, 0)
 
+	err = r.queryUsers(ctx, models.Pagination{Offset: page.Offset, Limit: page.Limit + 1, Cursor: page.Cursor},
+		func(user *models.User) error {
+			users = append(users, user)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(users) > page.Limit {
+		users = users[:page.Limit]
+		info.NextCursor = models.EncodeCursor(users[page.Limit-1].ID)
+	}
+
+	return users, info, nil
+}
+
+func (r *Repository) StreamUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error {
+	return r.queryUsers(ctx, page, fn)
+}
+
+// queryUsers calls fn for the users of page, ordered by id, as the rows are read. A zero limit reads them all.
+func (r *Repository) queryUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error {
+	args := make([]interface{}, 0)
+	q := "SELECT id,name,role FROM users"
+
+	if page.Cursor != "" {
+		key, ok := models.DecodeCursor(page.Cursor, 1)
+		if !ok {
+			return wallet.ErrInvalidCursor
+		}
+
+		args = append(args, key[0])
+		q += " WHERE id>$1"
+	}
+
+	q += " ORDER BY id" + limit(page, &args)
+
+	rows, err := r.Conn.QueryContext(ctx, q, args...)
+	if err != nil {
+		if isInvalidText(err) {
+			return wallet.ErrInvalidCursor
+		}
+
+		return errors.Wrap(err, "Error from db")
+	}
+
+	defer rows.Close()
+
 	for rows.Next() {
 		user := new(models.User)
-		err := rows.Scan(&user.ID, &user.Token)
+		err := rows.Scan(&user.ID, &user.Name, &user.Role)
 
 		if err != nil {
-			return nil, errors.Wrap(err, "Error from db")
+			return errors.Wrap(err, "Error from db")
 		}
 
-		users = append(users, user)
+		err = fn(user)
+		if err != nil {
+			return err
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, errors.Wrap(err, "Error from db")
+		return errors.Wrap(err, "Error from db")
 	}
 
-	return users, nil
+	return nil
+}
+
+// limit returns the LIMIT and OFFSET clauses of page and appends their arguments. A zero limit has no LIMIT.
+func limit(page models.Pagination, args *[]interface{}) string {
+	clause := ""
+
+	if page.Limit > 0 {
+		*args = append(*args, page.Limit)
+		clause += fmt.Sprintf(" LIMIT $%d", len(*args))
+	}
+
+	*args = append(*args, page.Offset)
+
+	return clause + fmt.Sprintf(" OFFSET $%d", len(*args))
 }
 
-func (r *Repository) CreateWallet(wallet *models.Wallet) error {
-	q := "INSERT INTO wallets (balance, user_id) VALUES ($1,$2)"
-	_, err := r.Conn.Exec(q, wallet.Balance, wallet.UserID)
+func (r *Repository) CreateWallet(ctx context.Context, w *models.Wallet) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	q := "INSERT INTO wallets (balance, user_id, currency) VALUES ($1,$2,$3) RETURNING id"
 
+	err = tx.QueryRowContext(ctx, q, w.Balance, w.UserID, w.Currency).Scan(&w.ID)
+	if err != nil {
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	// The balance is already set on the row, only the journal needs the opening postings.
+	err = insertEntries(ctx, tx, wallet.OpeningEntries(w), time.Now())
+	if err != nil {
+		return rollback(tx, err)
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return errors.Wrap(err, "Error from db")
 	}
@@ -79,79 +203,228 @@ func (r *Repository) CreateWallet(wallet *models.Wallet) error {
 	return nil
 }
 
-func (r *Repository) GetWalletByID(id string) (*models.Wallet, error) {
-	q := "SELECT id,balance,user_id FROM wallets WHERE id=$1"
-	wallet := new(models.Wallet)
-	err := r.Conn.QueryRow(q, id).Scan(&wallet.ID, &wallet.Balance, &wallet.UserID)
+func (r *Repository) GetWalletByID(ctx context.Context, id string) (*models.Wallet, error) {
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
+	w := new(models.Wallet)
+	err := r.Conn.QueryRowContext(ctx, q, id).Scan(&w.ID, &w.Balance, &w.UserID, &w.Currency)
+
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return nil, wallet.ErrWalletNotFound
+	}
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	return wallet, nil
+	return w, nil
 }
 
-func (r *Repository) GetWalletTransactionsByID(id string) ([]*models.Transaction, error) {
-	rows, err := r.Conn.Query("SELECT * FROM transactions WHERE credit_wallet_id=$1 or debit_wallet_id=$1", id)
-	if err != nil {
-		return nil, errors.Wrap(err, "Error from db")
+func (r *Repository) GetWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	conditions, args := transactionConditions(id, filter)
+
+	return r.listTransactions(ctx, conditions, args, page)
+}
+
+func (r *Repository) GetTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	conditions, args := transactionConditions("", filter)
+
+	return r.listTransactions(ctx, conditions, args, page)
+}
+
+// transactionConditions translates the filter into conditions on indexed columns.
+// When walletID is set, only its transactions match and the counterparty is the other side.
+func transactionConditions(walletID string, filter models.TransactionFilter) ([]string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+
+		return fmt.Sprintf("$%d", len(args))
 	}
-	defer rows.Close()
 
-	transactions := make([]*models.Transaction, 0)
+	if walletID != "" {
+		w := arg(walletID)
 
-	for rows.Next() {
-		transaction := new(models.Transaction)
-		err := rows.Scan(&transaction.ID, &transaction.CreditWalletID, &transaction.DebitWalletID, &transaction.Amount,
-			&transaction.Type, &transaction.FeeAmount, &transaction.FeeWalletID,
-			&transaction.CreditUserID, &transaction.DebitUserID)
+		switch filter.Direction {
+		case models.DirectionIncoming:
+			conditions = append(conditions, "debit_wallet_id="+w)
+		case models.DirectionOutgoing:
+			conditions = append(conditions, "credit_wallet_id="+w)
+		default:
+			conditions = append(conditions, "(credit_wallet_id="+w+" or debit_wallet_id="+w+")")
+		}
 
-		if err != nil {
-			return nil, errors.Wrap(err, "Error from db")
+		if filter.CounterpartyWalletID != "" {
+			c := arg(filter.CounterpartyWalletID)
+			conditions = append(conditions, "((credit_wallet_id="+w+" and debit_wallet_id="+c+
+				") or (debit_wallet_id="+w+" and credit_wallet_id="+c+"))")
+		}
+
+		if filter.CounterpartyUserID != "" {
+			c := arg(filter.CounterpartyUserID)
+			conditions = append(conditions, "((credit_wallet_id="+w+" and debit_user_id="+c+
+				") or (debit_wallet_id="+w+" and credit_user_id="+c+"))")
+		}
+	} else {
+		if filter.CounterpartyWalletID != "" {
+			c := arg(filter.CounterpartyWalletID)
+			conditions = append(conditions, "(credit_wallet_id="+c+" or debit_wallet_id="+c+")")
 		}
 
-		transactions = append(transactions, transaction)
+		if filter.CounterpartyUserID != "" {
+			c := arg(filter.CounterpartyUserID)
+			conditions = append(conditions, "(credit_user_id="+c+" or debit_user_id="+c+")")
+		}
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, errors.Wrap(err, "Error from db")
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, "date>="+arg(filter.DateFrom))
+	}
+
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, "date<="+arg(filter.DateTo))
+	}
+
+	if filter.MinAmount != 0 {
+		conditions = append(conditions, "amount>="+arg(filter.MinAmount))
+	}
+
+	if filter.MaxAmount != 0 {
+		conditions = append(conditions, "amount<="+arg(filter.MaxAmount))
+	}
+
+	if filter.Type != nil {
+		conditions = append(conditions, "type="+arg(*filter.Type))
 	}
 
-	return transactions, nil
+	if filter.FeeWalletID != "" {
+		conditions = append(conditions, "fee_wallet_id="+arg(filter.FeeWalletID))
+	}
+
+	if filter.UserID != "" {
+		u := arg(filter.UserID)
+		conditions = append(conditions, "(credit_user_id="+u+" or debit_user_id="+u+")")
+	}
+
+	return conditions, args
 }
 
-func (r *Repository) GetTransactions() ([]*models.Transaction, error) {
-	rows, err := r.Conn.Query("SELECT * FROM transactions")
+// listTransactions returns a page of the transactions matching the conditions, ordered by date and id.
+// The keyset cursor holds the date and id of the last transaction of the previous page.
+func (r *Repository) listTransactions(ctx context.Context, conditions []string, args []interface{},
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	info := new(models.PageInfo)
+
+	err := r.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions"+where(conditions), args...).Scan(&info.Total)
 	if err != nil {
-		return nil, errors.Wrap(err, "Error from db")
-	}
+		if isInvalidText(err) {
+			return make([]*models.Transaction, 0), info, nil
+		}
 
-	defer rows.Close()
+		return nil, nil, errors.Wrap(err, "Error from db")
+	}
 
 	transactions := make([]*models.Transaction, 0)
 
-	for rows.Next() {
-		transaction := new(models.Transaction)
-		err := rows.Scan(&transaction.ID, &transaction.CreditWalletID, &transaction.DebitWalletID, &transaction.Amount,
-			&transaction.Type, &transaction.FeeAmount, &transaction.FeeWalletID,
-			&transaction.CreditUserID, &transaction.DebitUserID)
+	err = r.queryTransactions(ctx, conditions, args,
+		models.Pagination{Offset: page.Offset, Limit: page.Limit + 1, Cursor: page.Cursor},
+		func(transaction *models.Transaction) error {
+			transactions = append(transactions, transaction)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(transactions) > page.Limit {
+		transactions = transactions[:page.Limit]
+		last := transactions[page.Limit-1]
+		info.NextCursor = models.EncodeCursor(last.Date, last.ID)
+	}
+
+	return transactions, info, nil
+}
+
+func (r *Repository) StreamWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	conditions, args := transactionConditions(id, filter)
+
+	return r.queryTransactions(ctx, conditions, args, page, fn)
+}
+
+func (r *Repository) StreamTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	conditions, args := transactionConditions("", filter)
+
+	return r.queryTransactions(ctx, conditions, args, page, fn)
+}
+
+// queryTransactions calls fn for the transactions of page matching the conditions, ordered by date
+// and id, as the rows are read. A zero limit reads them all.
+func (r *Repository) queryTransactions(ctx context.Context, conditions []string, args []interface{},
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	if page.Cursor != "" {
+		key, ok := models.DecodeCursor(page.Cursor, 2)
+		if !ok {
+			return wallet.ErrInvalidCursor
+		}
 
+		args = append(args, key[0], key[1])
+		conditions = append(conditions, fmt.Sprintf("(date,id)>($%d,$%d)", len(args)-1, len(args)))
+	}
+
+	q := "SELECT " + transactionColumns + " FROM transactions" + where(conditions) + " ORDER BY date,id"
+	q += limit(page, &args)
+
+	rows, err := r.Conn.QueryContext(ctx, q, args...)
+	if err != nil {
+		switch {
+		case page.Cursor == "" && isInvalidText(err):
+			// A malformed id in the filter matches nothing.
+			return nil
+		case isInvalidText(err) || isInvalidDatetime(err):
+			return wallet.ErrInvalidCursor
+		}
+
+		return errors.Wrap(err, "Error from db")
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
 		if err != nil {
-			return nil, errors.Wrap(err, "Error from db")
+			return errors.Wrap(err, "Error from db")
 		}
 
-		transactions = append(transactions, transaction)
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, errors.Wrap(err, "Error from db")
+		return errors.Wrap(err, "Error from db")
 	}
 
-	return transactions, nil
+	return nil
 }
 
-func (r *Repository) CreateTransaction(transaction *models.Transaction) error {
-	ctx := context.Background()
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func (r *Repository) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if transaction.Amount <= 0 {
+		return wallet.ErrInvalidAmount
+	}
 
 	tx, err := r.Conn.BeginTx(ctx, nil)
 
@@ -159,115 +432,259 @@ func (r *Repository) CreateTransaction(transaction *models.Transaction) error {
 		return errors.Wrap(err, "Error from db")
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance=balance-$1 WHERE id=$2",
-		transaction.Amount+2, transaction.CreditWalletID)
+	err = lockWallets(ctx, tx, transaction)
+	if err != nil {
+		return rollback(tx, err)
+	}
 
+	if transaction.OriginalTransactionID != "" {
+		err = refund(ctx, tx, transaction)
+		if err != nil {
+			return rollback(tx, err)
+		}
+	}
+
+	now := time.Now()
+
+	row := tx.QueryRowContext(ctx, "INSERT INTO transactions (credit_wallet_id,debit_wallet_id,amount,currency,"+
+		"debit_amount,debit_currency,rate,type,fee_amount,fee_wallet_id,fee_wallet_amount,fee_currency,credit_user_id,"+
+		"debit_user_id,date,idempotency_key,original_transaction_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,"+
+		"(SELECT user_id FROM wallets WHERE id=$1),(SELECT user_id FROM wallets WHERE id=$2),$13,$14,$15) "+
+		"RETURNING "+transactionColumns,
+		transaction.CreditWalletID, transaction.DebitWalletID, transaction.Amount, transaction.Currency,
+		transaction.DebitAmount, transaction.DebitCurrency, transaction.Rate, transaction.Type, transaction.FeeAmount,
+		transaction.FeeWalletID, transaction.FeeWalletAmount, transaction.FeeCurrency, now,
+		nullString(transaction.IdempotencyKey), nullString(transaction.OriginalTransactionID))
+
+	created, err := scanTransaction(row)
 	if err != nil {
-		if rb := tx.Rollback(); rb != nil {
-			log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
+		if isUniqueViolation(err, idempotencyKeyConstraint) {
+			return rollback(tx, wallet.ErrDuplicateIdempotencyKey)
+		}
+
+		if isCheckViolation(err, amountConstraint) {
+			return rollback(tx, wallet.ErrInvalidAmount)
 		}
 
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	err = applyEntries(ctx, tx, wallet.TransferEntries(created), now)
+	if err != nil {
+		return rollback(tx, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
 		return errors.Wrap(err, "Error from db")
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance=balance+$1 WHERE id=$2",
-		transaction.Amount, transaction.DebitWalletID)
+	*transaction = *created
 
+	return nil
+}
+
+// lockWallets locks every wallet of the transaction in id order, so concurrent transfers
+// cannot deadlock, and checks that they exist and every wallet that pays can cover its postings.
+func lockWallets(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id,balance FROM wallets WHERE id IN ($1,$2,$3) ORDER BY id FOR UPDATE",
+		transaction.CreditWalletID, transaction.DebitWalletID, transaction.FeeWalletID)
 	if err != nil {
-		if rb := tx.Rollback(); rb != nil {
-			log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
+		if isInvalidText(err) {
+			return wallet.ErrWalletNotFound
 		}
 
 		return errors.Wrap(err, "Error from db")
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance=balance+$1 WHERE id=$2",
-		2, "85aa7525-4fdb-4436-a600-66ffc55e0f65")
-	if err != nil {
-		if rb := tx.Rollback(); rb != nil {
-			log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
+	defer rows.Close()
+
+	balances := make(map[string]int)
+
+	for rows.Next() {
+		var (
+			id      string
+			balance int
+		)
+
+		if err = rows.Scan(&id, &balance); err != nil {
+			return errors.Wrap(err, "Error from db")
 		}
 
+		balances[strings.ToLower(id)] = balance
+	}
+
+	if err = rows.Err(); err != nil {
 		return errors.Wrap(err, "Error from db")
 	}
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO transactions (credit_wallet_id,debit_wallet_id,amount,"+
-		"type,fee_amount,fee_wallet_id,credit_user_id, debit_user_id,date) VALUES "+
-		"($1,$2,$3,$4,$5,$6,(SELECT user_id FROM wallets WHERE id=$7),(SELECT user_id FROM wallets WHERE id=$8),"+
-		"$9)",
-		transaction.CreditWalletID, transaction.DebitWalletID, transaction.Amount, 1, 2,
-		"85aa7525-4fdb-4436-a600-66ffc55e0f65", transaction.CreditWalletID, transaction.DebitWalletID, time.Now())
-	if err != nil {
-		if rb := tx.Rollback(); rb != nil {
-			log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
+	for _, id := range []string{transaction.CreditWalletID, transaction.DebitWalletID, transaction.FeeWalletID} {
+		if _, ok := balances[strings.ToLower(id)]; !ok {
+			return wallet.ErrWalletNotFound
 		}
+	}
 
-		return errors.Wrap(err, "Error from db")
+	for _, entry := range wallet.TransferEntries(transaction) {
+		if !wallet.IsWalletAccount(entry.Account) {
+			continue
+		}
+
+		id := strings.ToLower(entry.Account)
+
+		balances[id] += entry.Amount
+		if balances[id] < 0 {
+			return wallet.ErrInsufficientFunds
+		}
+	}
+
+	return nil
+}
+
+// refund adds the reversal to the refunded totals of the original transaction. The update
+// locks the original, so concurrent reversals are checked one after another.
+func refund(ctx context.Context, tx *sql.Tx, reversal *models.Transaction) error {
+	row := tx.QueryRowContext(ctx, "UPDATE transactions SET refunded_amount=refunded_amount+$1,"+
+		"refunded_fee_amount=refunded_fee_amount+$2,refunded_fee_wallet_amount=refunded_fee_wallet_amount+$3 "+
+		"WHERE id=$4 RETURNING "+transactionColumns,
+		reversal.DebitAmount, reversal.FeeAmount, reversal.FeeWalletAmount, reversal.OriginalTransactionID)
+
+	original, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return wallet.ErrTransactionNotFound
 	}
 
-	err = tx.Commit()
 	if err != nil {
 		return errors.Wrap(err, "Error from db")
 	}
 
-	return nil
+	return wallet.CheckRefunds(original, reversal)
 }
 
-func (r *Repository) GetWalletAmountDayByID(id string, week models.Week) ([]*models.Day, error) {
-	q1 := "SELECT date_trunc('day',date) as day, SUM(amount) FROM transactions WHERE credit_wallet_id=$1 " +
-		"AND date >= $2 AND date <= $3 GROUP BY date_trunc('day',date)"
-	//badValue := 0
-	days := make([]*models.Day, 0)
-	rows, err := r.Conn.Query(q1, id, week.DateFrom, week.DateTo)
+// applyEntries moves the cached balance of every wallet account by its posting and writes the postings to the journal.
+func applyEntries(ctx context.Context, tx *sql.Tx, entries []*models.LedgerEntry, date time.Time) error {
+	for _, entry := range entries {
+		if !wallet.IsWalletAccount(entry.Account) {
+			continue
+		}
 
-	for rows.Next() {
-		day := new(models.Day)
-		err := rows.Scan(&day.Date, &day.Outcome)
+		_, err := tx.ExecContext(ctx, "UPDATE wallets SET balance=balance+$1 WHERE id=$2", entry.Amount, entry.Account)
+		if isCheckViolation(err, balanceConstraint) {
+			return wallet.ErrInsufficientFunds
+		}
 
 		if err != nil {
-			return nil, errors.Wrap(err, "Error from db")
+			return errors.Wrap(err, "Error from db")
 		}
+	}
 
-		days = append(days, day)
+	return insertEntries(ctx, tx, entries, date)
+}
+
+func insertEntries(ctx context.Context, tx *sql.Tx, entries []*models.LedgerEntry, date time.Time) error {
+	for _, entry := range entries {
+		_, err := tx.ExecContext(ctx, "INSERT INTO ledger_entries (transaction_id,account,amount,currency,date) "+
+			"VALUES ($1,$2,$3,$4,$5)", nullString(entry.TransactionID), entry.Account, entry.Amount, entry.Currency, date)
+		if err != nil {
+			return errors.Wrap(err, "Error from db")
+		}
 	}
 
-	if err = rows.Err(); err != nil {
+	return nil
+}
+
+func (r *Repository) GetLedgerEntriesByWalletID(ctx context.Context, id string) ([]*models.LedgerEntry, error) {
+	rows, err := r.Conn.QueryContext(ctx, "SELECT id,transaction_id,account,amount,currency,date,"+
+		"SUM(amount) OVER (ORDER BY id) FROM ledger_entries WHERE account=$1 ORDER BY id", strings.ToLower(id))
+	if err != nil {
 		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	q2 := "SELECT date_trunc('day',date), SUM(amount) FROM transactions WHERE debit_wallet_id=$1 " +
-		"AND date >= $2 AND date <= $3 GROUP BY date_trunc('day',date)"
+	defer rows.Close()
+
+	entries := make([]*models.LedgerEntry, 0)
 
-	rows, err = r.Conn.Query(q2, id, week.DateFrom, week.DateTo)
 	for rows.Next() {
-		incday := new(models.Day)
-		err := rows.Scan(&incday.Date, &incday.Income)
+		entry := new(models.LedgerEntry)
+
+		var transactionID sql.NullString
 
+		err := rows.Scan(&entry.ID, &transactionID, &entry.Account, &entry.Amount, &entry.Currency, &entry.Date,
+			&entry.Balance)
 		if err != nil {
 			return nil, errors.Wrap(err, "Error from db")
 		}
 
-		for _, day := range days {
-			if day.Date == incday.Date {
-				day.Income = incday.Income
-			}
-		}
+		entry.TransactionID = transactionID.String
+		entries = append(entries, entry)
 	}
 
-	return days, nil
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return entries, nil
+}
+
+func (r *Repository) GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error) {
+	row := r.Conn.QueryRowContext(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE id=$1", id)
+
+	transaction, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return transaction, nil
 }
 
-func (r *Repository) GetWalletAmountWeekByID(id string, week models.Week) ([]*models.Day, error) {
-	q1 := "SELECT date_trunc('week',date) as week,sum(amount) FROM transactions WHERE credit_wallet_id=$1 " +
-		"AND date >= $2 AND date <= $3 GROUP BY date_trunc('week',date)"
-	//badValue := 0
+func (r *Repository) GetTransactionByIdempotencyKey(ctx context.Context, walletID, key string) (*models.Transaction, error) {
+	row := r.Conn.QueryRowContext(ctx, "SELECT "+transactionColumns+
+		" FROM transactions WHERE credit_wallet_id=$1 AND idempotency_key=$2", walletID, key)
+
+	transaction, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, wallet.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return transaction, nil
+}
+
+func (r *Repository) GetWalletAmountDayByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, "day")
+}
+
+func (r *Repository) GetWalletAmountWeekByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	return r.aggregate(ctx, id, week, "week")
+}
+
+// aggregate sums per unit of time between the dates of week what the wallet sent, in its own currency,
+// and what it received, in the currency of the receiver. Periods are returned oldest first.
+func (r *Repository) aggregate(ctx context.Context, id string, week models.Week, unit string) ([]*models.Day, error) {
+	q := "SELECT date_trunc('" + unit + "',date) AS period," +
+		"SUM(CASE WHEN credit_wallet_id=$1 THEN amount ELSE 0 END)," +
+		"SUM(CASE WHEN debit_wallet_id=$1 THEN debit_amount ELSE 0 END) FROM transactions " +
+		"WHERE (credit_wallet_id=$1 OR debit_wallet_id=$1) AND date >= $2 AND date <= $3 GROUP BY period ORDER BY period"
+
+	rows, err := r.Conn.QueryContext(ctx, q, id, week.DateFrom, week.DateTo)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	defer rows.Close()
+
 	days := make([]*models.Day, 0)
-	rows, err := r.Conn.Query(q1, id, week.DateFrom, week.DateTo)
 
 	for rows.Next() {
 		day := new(models.Day)
-		err := rows.Scan(&day.Date, &day.Outcome)
 
+		err := rows.Scan(&day.Date, &day.Outcome, &day.Income)
 		if err != nil {
 			return nil, errors.Wrap(err, "Error from db")
 		}
@@ -279,24 +696,69 @@ func (r *Repository) GetWalletAmountWeekByID(id string, week models.Week) ([]*mo
 		return nil, errors.Wrap(err, "Error from db")
 	}
 
-	q2 := "SELECT date_trunc('week',date) as week,sum(amount) FROM transactions WHERE debit_wallet_id=$1 " +
-		"AND date >= $2 AND date <= $3 GROUP BY date_trunc('week',date)"
+	return days, nil
+}
 
-	rows, err = r.Conn.Query(q2, id, week.DateFrom, week.DateTo)
-	for rows.Next() {
-		incday := new(models.Day)
-		err := rows.Scan(&incday.Date, &incday.Income)
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-		if err != nil {
-			return nil, errors.Wrap(err, "Error from db")
-		}
+func scanTransaction(row scanner) (*models.Transaction, error) {
+	transaction := new(models.Transaction)
 
-		for _, day := range days {
-			if day.Date == incday.Date {
-				day.Income = incday.Income
-			}
-		}
+	var idempotencyKey, originalTransactionID sql.NullString
+
+	err := row.Scan(&transaction.ID, &transaction.CreditWalletID, &transaction.DebitWalletID, &transaction.Amount,
+		&transaction.Currency, &transaction.DebitAmount, &transaction.DebitCurrency, &transaction.Rate,
+		&transaction.Type, &transaction.FeeAmount, &transaction.FeeWalletID, &transaction.FeeWalletAmount,
+		&transaction.FeeCurrency, &transaction.CreditUserID, &transaction.DebitUserID, &transaction.Date,
+		&idempotencyKey, &originalTransactionID, &transaction.RefundedAmount, &transaction.RefundedFeeAmount,
+		&transaction.RefundedFeeWalletAmount)
+	if err != nil {
+		return nil, err
 	}
 
-	return days, nil
+	transaction.IdempotencyKey = idempotencyKey.String
+	transaction.OriginalTransactionID = originalTransactionID.String
+
+	return transaction, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
+
+func isCheckViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == checkViolation && pqErr.Constraint == constraint
+}
+
+// isInvalidText reports a malformed value such as an id that is not a uuid.
+func isInvalidText(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == invalidTextRepresention
+}
+
+func isInvalidDatetime(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == invalidDatetimeFormat
+}
+
+// rollback aborts tx and returns err, along with the reason the abort failed.
+func rollback(tx *sql.Tx, err error) error {
+	// The transaction is already rolled back when its context was canceled.
+	if rb := tx.Rollback(); rb != nil && !errors.Is(rb, sql.ErrTxDone) {
+		return errors.WithMessagef(err, "unable to abort: %v", rb)
+	}
+
+	return err
 }
@@ -0,0 +1,124 @@
+package postgre_test
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/migrate"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/repository/postgre"
+	"github.com/workshops/wallet/internal/repository/repotest"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"github.com/workshops/wallet/migrations"
+)
+
+const systemUserID = "66aeb414-335a-4d1d-9dd9-6622b9c179a9"
+
+// TestConformance migrates the database, then empties it before every check.
+//nolint
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("WALLET_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("WALLET_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := postgre.NewPostgresDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := postgre.NewMigrator(db, migrations.Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrate.To(context.Background(), migrator, migrate.Latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repotest.Run(t, func(t *testing.T) wallet.Repository {
+		for _, query := range []string{
+			"TRUNCATE ledger_entries, transactions, wallets, users CASCADE",
+			"INSERT INTO users (id) VALUES ('" + systemUserID + "')",
+			"INSERT INTO wallets (id, balance, user_id, currency) VALUES ('" + repotest.FeeWalletID + "', 0, '" +
+				systemUserID + "', 'USD')",
+		} {
+			_, err := db.Exec(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return postgre.NewRepository(db)
+	})
+}
+
+//nolint
+func TestStreamTransactions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+
+	columns := []string{"id", "credit_wallet_id", "debit_wallet_id", "amount", "currency", "debit_amount",
+		"debit_currency", "rate", "type", "fee_amount", "fee_wallet_id", "fee_wallet_amount", "fee_currency",
+		"credit_user_id", "debit_user_id", "date", "idempotency_key", "original_transaction_id", "refunded_amount",
+		"refunded_fee_amount", "refunded_fee_wallet_amount"}
+	rows := sqlmock.NewRows(columns)
+	for _, id := range []string{"t1", "t2"} {
+		rows.AddRow(id, "w1", "w2", 100, "USD", 100, "USD", 1.0, 0, 10, repotest.FeeWalletID, 10, "USD",
+			"u1", "u2", "2022-08-01T10:00:00Z", nil, nil, 0, 0, 0)
+	}
+
+	// Streams read without a LIMIT and skip the COUNT of the list.
+	mock.ExpectQuery(`FROM transactions WHERE amount>=\$1 ORDER BY date,id OFFSET \$2$`).
+		WithArgs(100, 0).WillReturnRows(rows)
+
+	ids := make([]string, 0)
+	err = postgre.NewRepository(db).StreamTransactions(context.Background(), models.TransactionFilter{MinAmount: 100},
+		models.Pagination{}, func(transaction *models.Transaction) error {
+			ids = append(ids, transaction.ID)
+			return nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t1", "t2"}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionNonPositiveAmount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	// The amount is refused before the wallets are locked.
+	err = repo.CreateTransaction(context.Background(), &models.Transaction{CreditWalletID: "w1", DebitWalletID: "w2", Amount: -500})
+	assert.ErrorIs(t, err, wallet.ErrInvalidAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The check constraint stands behind it.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id,balance FROM wallets WHERE id IN ($1,$2,$3) ORDER BY id FOR UPDATE")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(repotest.FeeWalletID, 0).AddRow("w1", 1000).AddRow("w2", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO transactions")).
+		WillReturnError(&pq.Error{Code: "23514", Constraint: "transactions_amount_check"})
+	mock.ExpectRollback()
+
+	err = repo.CreateTransaction(context.Background(), &models.Transaction{CreditWalletID: "w1", DebitWalletID: "w2",
+		Amount: 100, FeeWalletID: repotest.FeeWalletID})
+	assert.ErrorIs(t, err, wallet.ErrInvalidAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
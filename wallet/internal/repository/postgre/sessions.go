@@ -0,0 +1,145 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+const refreshTokenColumns = "hash,family_id,user_id,access_token_id,expires_at,used,revoked"
+
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	user := new(models.User)
+	err := r.Conn.QueryRowContext(ctx, "SELECT id,name,role FROM users WHERE id=$1", id).
+		Scan(&user.ID, &user.Name, &user.Role)
+
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return nil, wallet.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return user, nil
+}
+
+func (r *Repository) SetUserRole(ctx context.Context, id, role string) error {
+	result, err := r.Conn.ExecContext(ctx, "UPDATE users SET role=$1 WHERE id=$2", role, id)
+	if isInvalidText(err) {
+		return wallet.ErrUserNotFound
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	if updated == 0 {
+		return wallet.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(ctx, r.Conn, token)
+}
+
+func (r *Repository) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	q := "SELECT " + refreshTokenColumns + " FROM refresh_tokens WHERE hash=$1"
+	token := new(models.RefreshToken)
+
+	err := r.Conn.QueryRowContext(ctx, q, hash).Scan(&token.Hash, &token.FamilyID, &token.UserID,
+		&token.AccessTokenID, &token.ExpiresAt, &token.Used, &token.Revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, wallet.ErrRefreshTokenNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error from db")
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken only marks the token used while it is unused, so of two concurrent
+// renewals with the same token one fails as a reuse.
+func (r *Repository) RotateRefreshToken(ctx context.Context, hash string, next *models.RefreshToken) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used=true WHERE hash=$1 AND NOT used", hash)
+	if err != nil {
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return rollback(tx, errors.Wrap(err, "Error from db"))
+	}
+
+	if n == 0 {
+		return rollback(tx, wallet.ErrRefreshTokenReused)
+	}
+
+	err = insertRefreshToken(ctx, tx, next)
+	if err != nil {
+		return rollback(tx, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func (r *Repository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.Conn.ExecContext(ctx, "UPDATE refresh_tokens SET revoked=true WHERE family_id=$1", familyID)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
+
+func (r *Repository) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var revoked bool
+
+	err := r.Conn.QueryRowContext(ctx, "SELECT revoked FROM refresh_tokens WHERE access_token_id=$1", id).Scan(&revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, "Error from db")
+	}
+
+	return revoked, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *models.RefreshToken) error {
+	q := "INSERT INTO refresh_tokens (hash,family_id,user_id,access_token_id,expires_at) VALUES ($1,$2,$3,$4,$5)"
+
+	_, err := db.ExecContext(ctx, q, token.Hash, token.FamilyID, token.UserID, token.AccessTokenID, token.ExpiresAt)
+	if err != nil {
+		return errors.Wrap(err, "Error from db")
+	}
+
+	return nil
+}
@@ -0,0 +1,669 @@
+// Package repotest is a conformance suite for wallet.Repository implementations. Every backend
+// runs it from its own tests, so they all behave the same behind the service.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/config"
+	"github.com/workshops/wallet/internal/repository/models"
+	feepolicy "github.com/workshops/wallet/internal/services/fee"
+	"github.com/workshops/wallet/internal/services/rate"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// FeeWalletID is the wallet the transfers of the suite pay their fees to.
+const FeeWalletID = "85aa7525-4fdb-4436-a600-66ffc55e0f65"
+
+// unknownID is a well formed id that no backend hands out.
+const unknownID = "00000000-0000-4000-8000-000000000000"
+
+const fee = 10
+
+// Factory returns an empty repository that only holds the USD fee wallet FeeWalletID with a zero balance.
+type Factory func(t *testing.T) wallet.Repository
+
+// Run checks the repository against the behaviour the service relies on. Every check gets a fresh repository.
+func Run(t *testing.T, newRepository Factory) {
+	checks := []struct {
+		name string
+		run  func(*testing.T, wallet.Repository)
+	}{
+		{"Users", testUsers},
+		{"Accounts", testAccounts},
+		{"Roles", testRoles},
+		{"RefreshTokens", testRefreshTokens},
+		{"Wallets", testWallets},
+		{"WalletNotFound", testWalletNotFound},
+		{"Transfer", testTransfer},
+		{"InsufficientFunds", testInsufficientFunds},
+		{"NonPositiveAmount", testNonPositiveAmount},
+		{"UnknownWallet", testUnknownWallet},
+		{"IdempotencyKey", testIdempotencyKey},
+		{"TransactionNotFound", testTransactionNotFound},
+		{"Reversal", testReversal},
+		{"ListTransactions", testListTransactions},
+		{"Stream", testStream},
+		{"Aggregation", testAggregation},
+	}
+
+	for _, c := range checks {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newRepository(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, repo wallet.Repository, name string) *models.User {
+	user := &models.User{Name: name, PasswordHash: "hash of " + name}
+
+	require.NoError(t, repo.CreateUser(context.Background(), user))
+	require.NotEmpty(t, user.ID)
+
+	return user
+}
+
+func newWallet(t *testing.T, repo wallet.Repository, userID string, balance int) string {
+	w := &models.Wallet{Balance: balance, UserID: userID, Currency: "USD"}
+
+	require.NoError(t, repo.CreateWallet(context.Background(), w))
+	require.NotEmpty(t, w.ID)
+
+	return w.ID
+}
+
+func balance(t *testing.T, repo wallet.Repository, id string) int {
+	w, err := repo.GetWalletByID(context.Background(), id)
+	require.NoError(t, err)
+
+	return w.Balance
+}
+
+// transfer builds a USD transfer the way the service does, with a flat fee.
+func transfer(from, to string, amount int) *models.Transaction {
+	return &models.Transaction{
+		CreditWalletID:  from,
+		DebitWalletID:   to,
+		Amount:          amount,
+		Currency:        "USD",
+		DebitAmount:     amount,
+		DebitCurrency:   "USD",
+		Rate:            1,
+		Type:            wallet.PriorityLow,
+		FeeAmount:       fee,
+		FeeWalletID:     FeeWalletID,
+		FeeWalletAmount: fee,
+		FeeCurrency:     "USD",
+	}
+}
+
+func testUsers(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+
+	alice := newUser(t, repo, "alice")
+	bob := newUser(t, repo, "bob")
+
+	users, info, err := repo.GetUsers(ctx, models.Pagination{Limit: 100})
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, info.Total, 2)
+	assert.Contains(t, users, &models.User{ID: alice.ID, Name: "alice", Role: models.RoleUser}, "lists leave out password hashes")
+	assert.Contains(t, users, &models.User{ID: bob.ID, Name: "bob", Role: models.RoleUser})
+
+	first, info, err := repo.GetUsers(ctx, models.Pagination{Limit: 1})
+
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	require.NotEmpty(t, info.NextCursor)
+
+	next, _, err := repo.GetUsers(ctx, models.Pagination{Limit: 1, Cursor: info.NextCursor})
+
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.NotEqual(t, first[0].ID, next[0].ID)
+}
+
+func testAccounts(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	alice := newUser(t, repo, "alice")
+
+	found, err := repo.GetUserByName(ctx, "alice")
+
+	require.NoError(t, err)
+	assert.Equal(t, alice, found)
+
+	err = repo.CreateUser(ctx, &models.User{Name: "alice", PasswordHash: "another hash"})
+
+	assert.ErrorIs(t, err, wallet.ErrUserExists)
+
+	_, err = repo.GetUserByName(ctx, "nobody")
+
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+
+	// Users from before passwords may share a name and cannot log in.
+	require.NoError(t, repo.CreateUser(ctx, &models.User{Name: "legacy"}))
+	require.NoError(t, repo.CreateUser(ctx, &models.User{Name: "legacy"}))
+
+	_, err = repo.GetUserByName(ctx, "legacy")
+
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+}
+
+func testRoles(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	alice := newUser(t, repo, "alice")
+	root := &models.User{Name: "root", PasswordHash: "hash of root", Role: models.RoleAdmin}
+
+	require.NoError(t, repo.CreateUser(ctx, root))
+	assert.Equal(t, models.RoleUser, alice.Role, "users are created with the user role")
+
+	require.NoError(t, repo.SetUserRole(ctx, alice.ID, models.RoleSupport))
+
+	found, err := repo.GetUserByID(ctx, alice.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleSupport, found.Role)
+
+	found, err = repo.GetUserByName(ctx, "root")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, found.Role)
+
+	err = repo.SetUserRole(ctx, unknownID, models.RoleAdmin)
+
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+}
+
+func testRefreshTokens(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	alice := newUser(t, repo, "alice")
+
+	found, err := repo.GetUserByID(ctx, alice.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.User{ID: alice.ID, Name: "alice", Role: models.RoleUser}, found)
+
+	_, err = repo.GetUserByID(ctx, unknownID)
+
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	first := &models.RefreshToken{Hash: "first", FamilyID: "family", UserID: alice.ID, AccessTokenID: "jti-1", ExpiresAt: expires}
+	second := &models.RefreshToken{Hash: "second", FamilyID: "family", UserID: alice.ID, AccessTokenID: "jti-2", ExpiresAt: expires}
+	other := &models.RefreshToken{Hash: "other", FamilyID: "other", UserID: alice.ID, AccessTokenID: "jti-3", ExpiresAt: expires}
+
+	require.NoError(t, repo.CreateRefreshToken(ctx, first))
+	require.NoError(t, repo.CreateRefreshToken(ctx, other))
+
+	stored, err := repo.GetRefreshToken(ctx, "first")
+
+	require.NoError(t, err)
+	assert.Equal(t, "family", stored.FamilyID)
+	assert.Equal(t, alice.ID, stored.UserID)
+	assert.Equal(t, "jti-1", stored.AccessTokenID)
+	assert.True(t, expires.Equal(stored.ExpiresAt))
+	assert.False(t, stored.Used)
+
+	_, err = repo.GetRefreshToken(ctx, "unknown")
+
+	assert.ErrorIs(t, err, wallet.ErrRefreshTokenNotFound)
+
+	require.NoError(t, repo.RotateRefreshToken(ctx, "first", second))
+
+	stored, err = repo.GetRefreshToken(ctx, "first")
+
+	require.NoError(t, err)
+	assert.True(t, stored.Used)
+
+	err = repo.RotateRefreshToken(ctx, "first", &models.RefreshToken{Hash: "third", FamilyID: "family",
+		UserID: alice.ID, AccessTokenID: "jti-4", ExpiresAt: expires})
+
+	assert.ErrorIs(t, err, wallet.ErrRefreshTokenReused)
+
+	_, err = repo.GetRefreshToken(ctx, "third")
+
+	assert.ErrorIs(t, err, wallet.ErrRefreshTokenNotFound, "a failed rotation stores nothing")
+
+	require.NoError(t, repo.RevokeTokenFamily(ctx, "family"))
+
+	for id, revoked := range map[string]bool{"jti-1": true, "jti-2": true, "jti-3": false, "unknown": false} {
+		got, err := repo.IsAccessTokenRevoked(ctx, id)
+
+		require.NoError(t, err)
+		assert.Equal(t, revoked, got, id)
+	}
+}
+
+func testWallets(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+
+	w := &models.Wallet{Balance: 100, UserID: user.ID, Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(ctx, w))
+
+	stored, err := repo.GetWalletByID(ctx, w.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, w, stored)
+
+	entries, err := repo.GetLedgerEntriesByWalletID(ctx, w.ID)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 100, entries[0].Amount)
+	assert.Equal(t, 100, entries[0].Balance)
+}
+
+func testWalletNotFound(t *testing.T, repo wallet.Repository) {
+	_, err := repo.GetWalletByID(context.Background(), unknownID)
+
+	assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+}
+
+func testTransfer(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	alice := newUser(t, repo, "alice")
+	bob := newUser(t, repo, "bob")
+	from := newWallet(t, repo, alice.ID, 1000)
+	to := newWallet(t, repo, bob.ID, 0)
+
+	transaction := transfer(from, to, 100)
+	require.NoError(t, repo.CreateTransaction(ctx, transaction))
+
+	assert.NotEmpty(t, transaction.ID)
+	assert.NotEmpty(t, transaction.Date)
+	assert.Equal(t, alice.ID, transaction.CreditUserID)
+	assert.Equal(t, bob.ID, transaction.DebitUserID)
+	assert.Equal(t, 890, balance(t, repo, from))
+	assert.Equal(t, 100, balance(t, repo, to))
+	assert.Equal(t, fee, balance(t, repo, FeeWalletID))
+
+	stored, err := repo.GetTransactionByID(ctx, transaction.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, transaction, stored)
+
+	entries, err := repo.GetLedgerEntriesByWalletID(ctx, from)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, -110, entries[1].Amount)
+	assert.Equal(t, 890, entries[1].Balance)
+}
+
+// testInsufficientFunds checks that a failed transfer leaves no trace.
+func testInsufficientFunds(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 100)
+	to := newWallet(t, repo, user.ID, 0)
+
+	err := repo.CreateTransaction(ctx, transfer(from, to, 100))
+
+	assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+	assert.Equal(t, 100, balance(t, repo, from))
+	assert.Equal(t, 0, balance(t, repo, to))
+	assert.Equal(t, 0, balance(t, repo, FeeWalletID))
+
+	transactions, info, err := repo.GetTransactions(ctx, models.TransactionFilter{}, models.Pagination{Limit: 10})
+
+	require.NoError(t, err)
+	assert.Empty(t, transactions)
+	assert.Equal(t, 0, info.Total)
+
+	entries, err := repo.GetLedgerEntriesByWalletID(ctx, from)
+
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// testNonPositiveAmount checks that the repository and the service refuse a transfer that would
+// take money from the receiver.
+func testNonPositiveAmount(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	mallory := newUser(t, repo, "mallory")
+	victim := newUser(t, repo, "victim")
+	from := newWallet(t, repo, mallory.ID, 10)
+	to := newWallet(t, repo, victim.ID, 1000)
+
+	fees, err := feepolicy.NewPolicy(&config.Fee{Percent: 1.5, WalletID: FeeWalletID})
+	require.NoError(t, err)
+
+	srvc := wallet.NewService(repo, fees, rate.NewStaticProvider("USD", nil), wallet.QueueConfig{}, wallet.Timeouts{})
+	defer srvc.Close()
+
+	caller := wallet.WithPrincipal(ctx, &wallet.Principal{UserID: mallory.ID, Role: models.RoleUser})
+
+	for _, amount := range []int{-500, 0} {
+		err := srvc.CreateTransaction(caller, &models.Transaction{CreditWalletID: from, DebitWalletID: to, Amount: amount})
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidAmount)
+
+		err = repo.CreateTransaction(ctx, transfer(from, to, amount))
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidAmount)
+	}
+
+	assert.Equal(t, 10, balance(t, repo, from))
+	assert.Equal(t, 1000, balance(t, repo, to))
+	assert.Equal(t, 0, balance(t, repo, FeeWalletID))
+}
+
+func testUnknownWallet(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+
+	err := repo.CreateTransaction(ctx, transfer(from, unknownID, 100))
+
+	assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	assert.Equal(t, 1000, balance(t, repo, from))
+	assert.Equal(t, 0, balance(t, repo, FeeWalletID))
+}
+
+func testIdempotencyKey(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+	to := newWallet(t, repo, user.ID, 0)
+
+	transaction := transfer(from, to, 100)
+	transaction.IdempotencyKey = "key"
+	require.NoError(t, repo.CreateTransaction(ctx, transaction))
+
+	stored, err := repo.GetTransactionByIdempotencyKey(ctx, from, "key")
+
+	require.NoError(t, err)
+	assert.Equal(t, transaction, stored)
+
+	// A backend that finds the wallet by an upper-case id must replay its keys by it too.
+	if _, err := repo.GetWalletByID(ctx, strings.ToUpper(from)); err == nil {
+		stored, err = repo.GetTransactionByIdempotencyKey(ctx, strings.ToUpper(from), "key")
+
+		require.NoError(t, err)
+		assert.Equal(t, transaction.ID, stored.ID)
+	}
+
+	duplicate := transfer(from, to, 100)
+	duplicate.IdempotencyKey = "key"
+
+	err = repo.CreateTransaction(ctx, duplicate)
+
+	assert.ErrorIs(t, err, wallet.ErrDuplicateIdempotencyKey)
+	assert.Equal(t, 890, balance(t, repo, from))
+	assert.Equal(t, 100, balance(t, repo, to))
+
+	_, err = repo.GetTransactionByIdempotencyKey(ctx, from, "other")
+
+	assert.ErrorIs(t, err, wallet.ErrTransactionNotFound)
+
+	// Keys are picked by clients, another sender may happen to use the same one.
+	_, err = repo.GetTransactionByIdempotencyKey(ctx, to, "key")
+
+	assert.ErrorIs(t, err, wallet.ErrTransactionNotFound)
+
+	other := transfer(to, from, 50)
+	other.IdempotencyKey = "key"
+	require.NoError(t, repo.CreateTransaction(ctx, other))
+
+	stored, err = repo.GetTransactionByIdempotencyKey(ctx, to, "key")
+
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, stored.ID)
+}
+
+func testTransactionNotFound(t *testing.T, repo wallet.Repository) {
+	_, err := repo.GetTransactionByID(context.Background(), unknownID)
+
+	assert.ErrorIs(t, err, wallet.ErrTransactionNotFound)
+}
+
+func testReversal(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+	to := newWallet(t, repo, user.ID, 100)
+
+	original := transfer(from, to, 100)
+	require.NoError(t, repo.CreateTransaction(ctx, original))
+
+	// The receiver pays the amount back; the fee is kept.
+	reversal := func() *models.Transaction {
+		r := transfer(to, from, 100)
+		r.FeeAmount = 0
+		r.FeeWalletAmount = 0
+		r.OriginalTransactionID = original.ID
+
+		return r
+	}
+
+	require.NoError(t, repo.CreateTransaction(ctx, reversal()))
+
+	assert.Equal(t, 990, balance(t, repo, from))
+	assert.Equal(t, 100, balance(t, repo, to))
+	assert.Equal(t, fee, balance(t, repo, FeeWalletID))
+
+	stored, err := repo.GetTransactionByID(ctx, original.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 100, stored.RefundedAmount)
+	assert.Equal(t, 0, stored.RefundedFeeAmount)
+
+	err = repo.CreateTransaction(ctx, reversal())
+
+	assert.ErrorIs(t, err, wallet.ErrAlreadyReversed)
+	assert.Equal(t, 990, balance(t, repo, from))
+	assert.Equal(t, 100, balance(t, repo, to))
+
+	// Refunding the fee too takes it back from the fee wallet.
+	charged := transfer(from, to, 100)
+	require.NoError(t, repo.CreateTransaction(ctx, charged))
+
+	refund := transfer(to, from, 50)
+	refund.FeeAmount = fee / 2
+	refund.FeeWalletAmount = fee / 2
+	refund.OriginalTransactionID = charged.ID
+	require.NoError(t, repo.CreateTransaction(ctx, refund))
+
+	assert.Equal(t, 3*fee/2, balance(t, repo, FeeWalletID))
+
+	stored, err = repo.GetTransactionByID(ctx, charged.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 50, stored.RefundedAmount)
+	assert.Equal(t, fee/2, stored.RefundedFeeAmount)
+	assert.Equal(t, fee/2, stored.RefundedFeeWalletAmount)
+
+	orphan := reversal()
+	orphan.OriginalTransactionID = unknownID
+
+	err = repo.CreateTransaction(ctx, orphan)
+
+	assert.ErrorIs(t, err, wallet.ErrTransactionNotFound)
+}
+
+func testListTransactions(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+	to := newWallet(t, repo, user.ID, 0)
+	other := newWallet(t, repo, user.ID, 1000)
+	bob := newUser(t, repo, "bob")
+	bobs := newWallet(t, repo, bob.ID, 1000)
+
+	for _, transaction := range []*models.Transaction{
+		transfer(from, to, 100),
+		transfer(from, to, 200),
+		transfer(from, to, 300),
+		transfer(other, to, 50),
+		transfer(bobs, other, 70),
+	} {
+		require.NoError(t, repo.CreateTransaction(ctx, transaction))
+	}
+
+	page, info, err := repo.GetWalletTransactionsByID(ctx, from, models.TransactionFilter{}, models.Pagination{Limit: 2})
+
+	require.NoError(t, err)
+	assert.Len(t, page, 2)
+	assert.Equal(t, 3, info.Total)
+	require.NotEmpty(t, info.NextCursor)
+
+	rest, info, err := repo.GetWalletTransactionsByID(ctx, from, models.TransactionFilter{},
+		models.Pagination{Limit: 2, Cursor: info.NextCursor})
+
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Empty(t, info.NextCursor)
+	assert.NotContains(t, page, rest[0])
+
+	incoming, info, err := repo.GetWalletTransactionsByID(ctx, to,
+		models.TransactionFilter{Direction: models.DirectionIncoming, MinAmount: 100}, models.Pagination{Limit: 10})
+
+	require.NoError(t, err)
+	assert.Len(t, incoming, 3)
+	assert.Equal(t, 3, info.Total)
+
+	outgoing, _, err := repo.GetWalletTransactionsByID(ctx, to,
+		models.TransactionFilter{Direction: models.DirectionOutgoing}, models.Pagination{Limit: 10})
+
+	require.NoError(t, err)
+	assert.Empty(t, outgoing)
+
+	all, info, err := repo.GetTransactions(ctx, models.TransactionFilter{}, models.Pagination{Limit: 10})
+
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+	assert.Equal(t, 5, info.Total)
+	assertDateOrder(t, all)
+
+	// Walking the pages by cursor lists the transactions in the same order.
+	walked := make([]*models.Transaction, 0, len(all))
+	cursor := ""
+
+	for {
+		page, info, err := repo.GetTransactions(ctx, models.TransactionFilter{}, models.Pagination{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+
+		walked = append(walked, page...)
+		if info.NextCursor == "" {
+			break
+		}
+
+		cursor = info.NextCursor
+	}
+
+	assert.Equal(t, all, walked)
+
+	bobsOwn, info, err := repo.GetTransactions(ctx, models.TransactionFilter{UserID: bob.ID}, models.Pagination{Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, bobsOwn, 1)
+	assert.Equal(t, 1, info.Total)
+	assert.Equal(t, bobs, bobsOwn[0].CreditWalletID)
+}
+
+// assertDateOrder checks that the transactions are ordered by (date, id), as every backend lists them.
+func assertDateOrder(t *testing.T, transactions []*models.Transaction) {
+	for i := 1; i < len(transactions); i++ {
+		previous, err := time.Parse(time.RFC3339Nano, transactions[i-1].Date)
+		require.NoError(t, err)
+
+		date, err := time.Parse(time.RFC3339Nano, transactions[i].Date)
+		require.NoError(t, err)
+
+		assert.True(t, previous.Before(date) || previous.Equal(date) && transactions[i-1].ID < transactions[i].ID,
+			"%s %s is listed before %s %s", transactions[i-1].Date, transactions[i-1].ID, transactions[i].Date, transactions[i].ID)
+	}
+}
+
+func testStream(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+	to := newWallet(t, repo, user.ID, 0)
+
+	for _, amount := range []int{100, 200, 300} {
+		require.NoError(t, repo.CreateTransaction(ctx, transfer(from, to, amount)))
+	}
+
+	listed, _, err := repo.GetWalletTransactionsByID(ctx, from, models.TransactionFilter{}, models.Pagination{Limit: 10})
+	require.NoError(t, err)
+
+	streamed := make([]*models.Transaction, 0)
+	collect := func(transaction *models.Transaction) error {
+		streamed = append(streamed, transaction)
+		return nil
+	}
+
+	require.NoError(t, repo.StreamWalletTransactionsByID(ctx, from, models.TransactionFilter{}, models.Pagination{}, collect))
+	assert.Equal(t, listed, streamed)
+
+	first, info, err := repo.GetWalletTransactionsByID(ctx, from, models.TransactionFilter{}, models.Pagination{Limit: 1})
+	require.NoError(t, err)
+
+	streamed = streamed[:0]
+	require.NoError(t, repo.StreamWalletTransactionsByID(ctx, from, models.TransactionFilter{},
+		models.Pagination{Cursor: info.NextCursor}, collect))
+	assert.Equal(t, listed[1:], streamed)
+	assert.NotContains(t, streamed, first[0])
+
+	streamed = streamed[:0]
+	require.NoError(t, repo.StreamTransactions(ctx, models.TransactionFilter{MinAmount: 200}, models.Pagination{Limit: 1}, collect))
+	assert.Len(t, streamed, 1)
+
+	stop := errors.New("client went away")
+	calls := 0
+	err = repo.StreamTransactions(ctx, models.TransactionFilter{}, models.Pagination{}, func(*models.Transaction) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+
+	users := make([]*models.User, 0)
+	require.NoError(t, repo.StreamUsers(ctx, models.Pagination{}, func(u *models.User) error {
+		users = append(users, u)
+		return nil
+	}))
+	assert.Contains(t, users, &models.User{ID: user.ID, Name: user.Name, Role: models.RoleUser})
+}
+
+func testAggregation(t *testing.T, repo wallet.Repository) {
+	ctx := context.Background()
+	user := newUser(t, repo, "alice")
+	from := newWallet(t, repo, user.ID, 1000)
+	to := newWallet(t, repo, user.ID, 0)
+
+	require.NoError(t, repo.CreateTransaction(ctx, transfer(from, to, 100)))
+	require.NoError(t, repo.CreateTransaction(ctx, transfer(from, to, 200)))
+
+	now := time.Now().UTC()
+	week := models.Week{DateFrom: now.AddDate(0, 0, -1).Format("2006-01-02"), DateTo: now.AddDate(0, 0, 1).Format("2006-01-02")}
+
+	days, err := repo.GetWalletAmountDayByID(ctx, from, week)
+
+	require.NoError(t, err)
+	require.Len(t, days, 1)
+	assert.Equal(t, 300, days[0].Outcome)
+	assert.Equal(t, 0, days[0].Income)
+
+	weeks, err := repo.GetWalletAmountWeekByID(ctx, to, week)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, weeks)
+
+	income := 0
+	for _, w := range weeks {
+		income += w.Income
+		assert.Equal(t, 0, w.Outcome)
+	}
+
+	assert.Equal(t, 300, income)
+}
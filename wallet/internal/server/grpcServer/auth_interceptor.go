@@ -4,20 +4,48 @@ import (
 	"context"
 
 	"github.com/workshops/wallet/internal/middleware/auth"
+	"github.com/workshops/wallet/internal/services/wallet"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
+// policies holds the policy of every method served over gRPC, by full method name, with the
+// policies of the matching HTTP routes. A method missing here is refused, so none is served
+// without a decision on who may call it.
+var policies = map[string]auth.Policy{
+	"/user.UserService/CreateUser":  auth.Public,
+	"/user.UserService/GetUsers":    auth.Staff,
+	"/user.UserService/SetUserRole": auth.Admin,
+
+	"/auth.AuthService/Login":   auth.Public,
+	"/auth.AuthService/Refresh": auth.Public,
+	"/auth.AuthService/Logout":  auth.Public,
+
+	"/wallet.WalletService/CreateWallet":  auth.Authenticated,
+	"/wallet.WalletService/GetWalletByID": auth.Authenticated,
+
+	"/transaction.TransactionService/GetTransactions":           auth.Authenticated,
+	"/transaction.TransactionService/CreateTransaction":         auth.Authenticated,
+	"/transaction.TransactionService/GetWalletTransactionsByID": auth.Authenticated,
+	"/transaction.TransactionService/ReverseTransaction":        auth.Staff,
+
+	// Reflection only describes the API, which tools like grpcurl read before any call.
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": auth.Public,
+}
+
 type AuthInterceptor struct {
-	jwtWrapper *auth.JwtWrapper
+	jwtWrapper  *auth.JwtWrapper
+	audience    string
+	revocations auth.Revocations
 }
 
-func NewAuthInterceptor(wrapper *auth.JwtWrapper) *AuthInterceptor {
-	return &AuthInterceptor{jwtWrapper: wrapper}
+// NewAuthInterceptor checks tokens with the wrapper and looks for revoked ones in revocations,
+// the service whose users log in over gRPC. Only tokens issued for audience, its backend, are accepted.
+func NewAuthInterceptor(wrapper *auth.JwtWrapper, audience string, revocations auth.Revocations) *AuthInterceptor {
+	return &AuthInterceptor{jwtWrapper: wrapper, audience: audience, revocations: revocations}
 }
 
+// Unary authorizes unary calls by the policy of their method.
 func (interceptor *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
@@ -25,7 +53,7 @@ func (interceptor *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		err := interceptor.authorize(ctx)
+		ctx, err := interceptor.authorize(ctx, info.FullMethod)
 		if err != nil {
 			return nil, err
 		}
@@ -34,20 +62,82 @@ func (interceptor *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
 	}
 }
 
-func (interceptor *AuthInterceptor) authorize(ctx context.Context) error {
-	md, ok := metadata.FromIncomingContext(ctx)
+// Stream authorizes streaming calls by the policy of their method.
+func (interceptor *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := interceptor.authorize(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// wrappedStream is a stream whose context was extended by an interceptor.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize checks the call against the policy of its method. Unless the method is public, it
+// validates the token of the authorization metadata and returns a context that carries its
+// claims and principal.
+func (interceptor *AuthInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	policy, ok := policies[method]
 	if !ok {
-		return status.Errorf(codes.Unauthenticated, "metadata is not provided")
+		return nil, statusError(ctx, wallet.ErrForbidden)
 	}
 
-	values := md["authorization"]
-	if len(values) == 0 {
-		return status.Errorf(codes.Unauthenticated, "authorization token is not provided")
+	if policy.IsPublic() {
+		return ctx, nil
 	}
-	accessToken := values[0]
-	_, err := interceptor.jwtWrapper.ValidateToken(accessToken)
+
+	accessToken := metadataToken(ctx)
+	if accessToken == "" {
+		return nil, statusError(ctx, unauthenticated("authorization token is not provided", nil))
+	}
+
+	authenticated, err := interceptor.jwtWrapper.Authenticate(ctx, accessToken, interceptor.audience, interceptor.revocations)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	principal, _ := wallet.PrincipalFrom(authenticated)
+
+	err = policy.Authorize(principal)
 	if err != nil {
-		return status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+		return nil, statusError(ctx, err)
 	}
-	return nil
+
+	return authenticated, nil
+}
+
+// metadataToken reads the token of the authorization metadata as the HTTP API reads the
+// Authorization header, with or without the Bearer scheme.
+func metadataToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	return auth.BearerToken(values[0])
+}
+
+func unauthenticated(message string, err error) error {
+	return &wallet.Error{Kind: wallet.KindUnauthenticated, Code: "unauthenticated", Message: message, Err: err}
 }
@@ -0,0 +1,157 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/middleware/auth"
+	pb "github.com/workshops/wallet/internal/proto"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+type noRevocations struct{}
+
+func (noRevocations) IsAccessTokenRevoked(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func newTestWrapper() *auth.JwtWrapper {
+	return auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+}
+
+func newTestInterceptor(t *testing.T) (*AuthInterceptor, func(role string) string) {
+	wrapper := newTestWrapper()
+
+	token := func(role string) string {
+		token, err := wrapper.GenerateToken("user-1", "alice", role, "token-1", "postgre")
+		require.NoError(t, err)
+
+		return token
+	}
+
+	return NewAuthInterceptor(wrapper, "postgre", noRevocations{}), token
+}
+
+func withAuthorization(value string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", value))
+}
+
+//nolint
+func TestPoliciesCoverEveryMethod(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{
+		pb.UserService_ServiceDesc,
+		pb.AuthService_ServiceDesc,
+		pb.WalletService_ServiceDesc,
+		pb.TransactionService_ServiceDesc,
+		reflectionpb.ServerReflection_ServiceDesc,
+	} {
+		for _, method := range desc.Methods {
+			assert.Contains(t, policies, "/"+desc.ServiceName+"/"+method.MethodName)
+		}
+		for _, stream := range desc.Streams {
+			assert.Contains(t, policies, "/"+desc.ServiceName+"/"+stream.StreamName)
+		}
+	}
+}
+
+//nolint
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor, token := newTestInterceptor(t)
+	otherToken, err := newTestWrapper().GenerateToken("user-1", "alice", "user", "token-1", "mongo")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{"public without token", context.Background(), "/user.UserService/CreateUser", codes.OK},
+		{"bearer token", withAuthorization("Bearer " + token("user")), "/wallet.WalletService/CreateWallet", codes.OK},
+		{"lowercase scheme", withAuthorization("bearer " + token("user")), "/wallet.WalletService/CreateWallet", codes.OK},
+		{"raw token", withAuthorization(token("user")), "/wallet.WalletService/CreateWallet", codes.OK},
+		{"no metadata", context.Background(), "/wallet.WalletService/CreateWallet", codes.Unauthenticated},
+		{"scheme only", withAuthorization("Bearer "), "/wallet.WalletService/CreateWallet", codes.Unauthenticated},
+		{"invalid token", withAuthorization("Bearer nonsense"), "/wallet.WalletService/CreateWallet", codes.Unauthenticated},
+		{"other backend", withAuthorization("Bearer " + otherToken), "/wallet.WalletService/CreateWallet", codes.Unauthenticated},
+		{"role too low", withAuthorization("Bearer " + token("user")), "/user.UserService/GetUsers", codes.PermissionDenied},
+		{"staff", withAuthorization("Bearer " + token("support")), "/user.UserService/GetUsers", codes.OK},
+		{"admin only", withAuthorization("Bearer " + token("support")), "/user.UserService/SetUserRole", codes.PermissionDenied},
+		{"method without policy", withAuthorization("Bearer " + token("admin")), "/wallet.WalletService/DeleteWallet", codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handlerCtx context.Context
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				handlerCtx = ctx
+				return "ok", nil
+			}
+
+			resp, err := interceptor.Unary()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+
+			if tt.code != codes.OK {
+				assert.Nil(t, handlerCtx)
+				return
+			}
+
+			assert.Equal(t, "ok", resp)
+			if policies[tt.method].IsPublic() {
+				return
+			}
+
+			claims, ok := auth.ClaimsFrom(handlerCtx)
+			require.True(t, ok)
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, "token-1", claims.Id)
+
+			principal, ok := wallet.PrincipalFrom(handlerCtx)
+			require.True(t, ok)
+			assert.Equal(t, &wallet.Principal{UserID: "user-1", Name: "alice", Role: claims.Role}, principal)
+		})
+	}
+}
+
+//nolint
+func TestStreamInterceptor(t *testing.T) {
+	interceptor, token := newTestInterceptor(t)
+	info := &grpc.StreamServerInfo{FullMethod: "/transaction.TransactionService/GetTransactions", IsServerStream: true}
+
+	var claims *auth.JwtClaim
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		claims, _ = auth.ClaimsFrom(stream.Context())
+		return nil
+	}
+
+	err := interceptor.Stream()(nil, &fakeStream{ctx: withAuthorization("Bearer " + token("user"))}, info, handler)
+	require.NoError(t, err)
+	require.NotNil(t, claims)
+	assert.Equal(t, "user-1", claims.Subject)
+
+	claims = nil
+	err = interceptor.Stream()(nil, &fakeStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Nil(t, claims)
+
+	info.FullMethod = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+	err = interceptor.Stream()(nil, &fakeStream{ctx: context.Background()}, info, handler)
+	assert.NoError(t, err)
+}
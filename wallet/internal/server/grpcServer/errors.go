@@ -0,0 +1,79 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/protobuf/proto" //nolint:staticcheck // status.WithDetails takes the v1 message
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain names the wallet service in the ErrorInfo of an error.
+const errorDomain = "wallet"
+
+// statusCode maps the kind of an error to a gRPC status code.
+func statusCode(kind wallet.Kind) codes.Code {
+	switch kind {
+	case wallet.KindInvalid:
+		return codes.InvalidArgument
+	case wallet.KindUnauthenticated:
+		return codes.Unauthenticated
+	case wallet.KindForbidden:
+		return codes.PermissionDenied
+	case wallet.KindNotFound:
+		return codes.NotFound
+	case wallet.KindConflict:
+		return codes.AlreadyExists
+	case wallet.KindRejected:
+		return codes.FailedPrecondition
+	case wallet.KindUnavailable:
+		return codes.Unavailable
+	case wallet.KindTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// invalidInput reports a request that fails validation. The validator names the offending fields.
+func invalidInput(err error) error {
+	return &wallet.Error{Kind: wallet.KindInvalid, Code: "validation_failed", Message: err.Error(), Err: err}
+}
+
+// statusError describes err as the HTTP error object does: the message goes in the status,
+// the machine-readable code in an ErrorInfo detail and the request ID in a RequestInfo detail.
+// Validation failures list their fields in a BadRequest detail.
+func statusError(ctx context.Context, err error) error {
+	e := wallet.AsError(err)
+	st := status.New(statusCode(e.Kind), e.Message)
+
+	details := []proto.Message{
+		&errdetails.ErrorInfo{Reason: e.Code, Domain: errorDomain},
+		&errdetails.RequestInfo{RequestId: apierror.RequestIDFrom(ctx)},
+	}
+
+	var fields validator.ValidationErrors
+	if errors.As(e.Err, &fields) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+		for _, field := range fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field(),
+				Description: field.Error(),
+			})
+		}
+
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	detailed, derr := st.WithDetails(details...)
+	if derr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
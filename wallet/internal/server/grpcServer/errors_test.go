@@ -0,0 +1,37 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//nolint
+func TestStatusError(t *testing.T) {
+	ctx := apierror.WithRequestID(context.Background(), "req-1")
+
+	st, ok := status.FromError(statusError(ctx, wallet.ErrWalletNotFound))
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "wallet not found", st.Message())
+
+	details := st.Details()
+	if assert.Len(t, details, 2) {
+		assert.Equal(t, "wallet_not_found", details[0].(*errdetails.ErrorInfo).GetReason())
+		assert.Equal(t, "wallet", details[0].(*errdetails.ErrorInfo).GetDomain())
+		assert.Equal(t, "req-1", details[1].(*errdetails.RequestInfo).GetRequestId())
+	}
+}
+
+//nolint
+func TestStatusErrorHidesInternalCause(t *testing.T) {
+	st, _ := status.FromError(statusError(context.Background(), assert.AnError))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+}
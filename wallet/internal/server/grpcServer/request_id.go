@@ -0,0 +1,48 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestID keeps the x-request-id metadata sent by the client or assigns a new ID,
+// and returns it in the response header.
+func RequestID(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	key, id := requestID(ctx)
+
+	// The header cannot be sent without a transport, e.g. when the handler is called directly.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(key, id))
+
+	return handler(apierror.WithRequestID(ctx, id), req)
+}
+
+// RequestIDStream is RequestID for streaming calls.
+func RequestIDStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	key, id := requestID(stream.Context())
+
+	_ = stream.SetHeader(metadata.Pairs(key, id))
+
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: apierror.WithRequestID(stream.Context(), id)})
+}
+
+// requestID returns the metadata key of the request ID and the ID the call goes by.
+func requestID(ctx context.Context) (string, string) {
+	key := strings.ToLower(apierror.RequestIDHeader)
+
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[key]) > 0 {
+		id = md[key][0]
+	}
+
+	if id == "" {
+		id = apierror.NewRequestID()
+	}
+
+	return key, id
+}
@@ -0,0 +1,65 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type headerStream struct {
+	fakeStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+//nolint
+func TestRequestIDStream(t *testing.T) {
+	interceptor, _ := newTestInterceptor(t)
+	info := &grpc.StreamServerInfo{FullMethod: "/transaction.TransactionService/GetTransactions", IsServerStream: true}
+	chain := func(srv interface{}, stream grpc.ServerStream, handler grpc.StreamHandler) error {
+		return RequestIDStream(srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return interceptor.Stream()(srv, stream, info, handler)
+		})
+	}
+
+	t.Run("keeps the client ID", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1"))
+		stream := &headerStream{fakeStream: fakeStream{ctx: ctx}}
+
+		err := chain(nil, stream, func(interface{}, grpc.ServerStream) error { return nil })
+		require.Error(t, err)
+
+		assert.Equal(t, []string{"req-1"}, stream.header.Get("x-request-id"))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		st := status.Convert(err)
+		require.Len(t, st.Details(), 2)
+		assert.Equal(t, "req-1", st.Details()[1].(*errdetails.RequestInfo).RequestId)
+	})
+
+	t.Run("assigns an ID", func(t *testing.T) {
+		stream := &headerStream{fakeStream: fakeStream{ctx: context.Background()}}
+		info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"}
+
+		var id string
+		err := RequestIDStream(nil, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+			id = apierror.RequestIDFrom(stream.Context())
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, id)
+		assert.Equal(t, []string{id}, stream.header.Get("x-request-id"))
+	})
+}
@@ -3,63 +3,120 @@ package grpcserver
 import (
 	"context"
 	"log"
+	"time"
 
-	"github.com/pkg/errors"
 	"github.com/workshops/wallet/internal/middleware/auth"
 	pb "github.com/workshops/wallet/internal/proto"
 	"github.com/workshops/wallet/internal/repository/models"
 	"github.com/workshops/wallet/internal/services/wallet"
 )
 
-// type Validator interface {
-//	Validate(interface{}) error
-//}
+// Validator checks a request by the validate tags of its model, as the HTTP API does.
+type Validator interface {
+	Validate(interface{}) error
+}
 
 type Server struct {
-	// valid Validator
-	service    *wallet.Service
+	valid   Validator
+	service *wallet.Service
+	// audience names the backend of service, the one that accepts the tokens issued here.
+	audience   string
 	jwtWrapper *auth.JwtWrapper
 	pb.UserServiceServer
+	pb.AuthServiceServer
 	pb.WalletServiceServer
 	pb.TransactionServiceServer
 }
 
-func NewGrpcServer(service *wallet.Service, jwtWrapper *auth.JwtWrapper) *Server {
+func NewGrpcServer(service *wallet.Service, audience string, jwtWrapper *auth.JwtWrapper,
+	validator Validator) *Server {
 	return &Server{
+		valid:      validator,
 		service:    service,
+		audience:   audience,
 		jwtWrapper: jwtWrapper,
 	}
 }
 
 func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
-	name := req.GetName()
+	credentials := models.Credentials{Name: req.GetName(), Password: req.GetPassword()}
 
-	token, err := s.jwtWrapper.GenerateToken(name)
+	err := s.valid.Validate(credentials)
 	if err != nil {
-		log.Printf("Unable to generate token: %v\n", err)
-
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, invalidInput(err))
 	}
 
-	err = s.service.CreateUser(token)
+	user, err := s.service.Register(ctx, credentials)
 	if err != nil {
 		log.Printf("Unable to create: %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	res := &pb.CreateUserResponse{
-		Name: name,
+		Id:   user.ID,
+		Name: user.Name,
 	}
 	return res, nil
 }
 
+// Login exchanges the name and password of a user for an access and a refresh token.
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, err := s.service.Authenticate(ctx, models.Credentials{Name: req.GetName(), Password: req.GetPassword()})
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	session, err := s.service.StartSession(ctx, user, s.jwtWrapper.RefreshTTL)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	tokens, err := s.jwtWrapper.SessionTokens(session, s.audience)
+	if err != nil {
+		log.Printf("Unable to generate token: %v\n", err)
+
+		return nil, statusError(ctx, err)
+	}
+
+	return &pb.LoginResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn}, nil
+}
+
+// Refresh exchanges a refresh token for new tokens. The refresh token cannot be used again.
+func (s *Server) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.RefreshResponse, error) {
+	session, err := s.service.RefreshSession(ctx, req.GetRefreshToken(), s.jwtWrapper.RefreshTTL)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	tokens, err := s.jwtWrapper.SessionTokens(session, s.audience)
+	if err != nil {
+		log.Printf("Unable to generate token: %v\n", err)
+
+		return nil, statusError(ctx, err)
+	}
+
+	return &pb.RefreshResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn}, nil
+}
+
+// Logout revokes the refresh token and every access token of its session.
+func (s *Server) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	err := s.service.EndSession(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &pb.LogoutResponse{}, nil
+}
+
 func (s *Server) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
-	users, err := s.service.GetUsers()
+	page := models.Pagination{Offset: int(req.GetOffset()), Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+
+	users, info, err := s.service.GetUsers(ctx, page)
 	if err != nil {
 		log.Printf("Unable to get users : %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	var protoUsers []*pb.User
@@ -69,28 +126,47 @@ func (s *Server) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.Get
 	}
 
 	res := &pb.GetUsersResponse{
-		User: protoUsers,
+		User:       protoUsers,
+		Total:      int32(info.Total),
+		NextCursor: info.NextCursor,
 	}
 
 	return res, nil
 }
 
+func (s *Server) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	user, err := s.service.SetUserRole(ctx, req.GetId(), req.GetRole())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &pb.SetUserRoleResponse{User: convertUser(user)}, nil
+}
+
 func (s *Server) CreateWallet(ctx context.Context, req *pb.CreateWalletRequest) (*pb.CreateWalletResponse, error) {
 	wallet := &models.Wallet{
-		Balance: int(req.GetBalance()),
-		UserID:  req.GetUserId(),
+		Balance:  int(req.GetBalance()),
+		UserID:   req.GetUserId(),
+		Currency: req.GetCurrency(),
 	}
 
-	err := s.service.CreateWallet(wallet)
+	err := s.valid.Validate(wallet)
+	if err != nil {
+		return nil, statusError(ctx, invalidInput(err))
+	}
+
+	err = s.service.CreateWallet(ctx, wallet)
 	if err != nil {
 		log.Printf("Unable to create: %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	res := &pb.CreateWalletResponse{
-		Balance: req.GetBalance(),
-		UserId:  req.GetUserId(),
+		Id:       wallet.ID,
+		Balance:  req.GetBalance(),
+		UserId:   req.GetUserId(),
+		Currency: wallet.Currency,
 	}
 
 	return res, nil
@@ -99,17 +175,18 @@ func (s *Server) CreateWallet(ctx context.Context, req *pb.CreateWalletRequest)
 func (s *Server) GetWalletByID(ctx context.Context, req *pb.GetWalledByIdRequest) (*pb.GetWalletByIdResponse, error) {
 	id := req.GetId()
 
-	wallet, err := s.service.GetWalletByID(id)
+	wallet, err := s.service.GetWalletByID(ctx, id)
 	if err != nil {
 		log.Printf("Unable to get wallet: %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	pbWallet := &pb.Wallet{
-		Id:      wallet.ID,
-		Balance: int32(wallet.Balance),
-		UserId:  wallet.UserID,
+		Id:       wallet.ID,
+		Balance:  int32(wallet.Balance),
+		UserId:   wallet.UserID,
+		Currency: wallet.Currency,
 	}
 
 	res := &pb.GetWalletByIdResponse{
@@ -121,11 +198,18 @@ func (s *Server) GetWalletByID(ctx context.Context, req *pb.GetWalledByIdRequest
 
 func (s *Server) GetTransactions(ctx context.Context,
 	req *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
-	transactions, err := s.service.GetTransactions()
+	page := models.Pagination{Offset: int(req.GetOffset()), Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+
+	filter, err := convertFilter(req.GetFilter())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	transactions, info, err := s.service.GetTransactions(ctx, filter, page)
 	if err != nil {
 		log.Printf("Unable to get transactions : %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	var protoTransactions []*pb.Transaction
@@ -136,6 +220,8 @@ func (s *Server) GetTransactions(ctx context.Context,
 
 	res := &pb.GetTransactionResponse{
 		Transaction: protoTransactions,
+		Total:       int32(info.Total),
+		NextCursor:  info.NextCursor,
 	}
 
 	return res, nil
@@ -147,33 +233,75 @@ func (s *Server) CreateTransaction(ctx context.Context,
 		CreditWalletID: req.GetCreditWalletId(),
 		DebitWalletID:  req.GetDebitWalletId(),
 		Amount:         int(req.GetAmount()),
+		IdempotencyKey: req.GetIdempotencyKey(),
+	}
+
+	err := s.valid.Validate(transaction)
+	if err != nil {
+		return nil, statusError(ctx, invalidInput(err))
 	}
 
-	err := s.service.CreateTransaction(transaction)
+	err = s.service.CreateTransaction(ctx, transaction)
 	if err != nil {
 		log.Printf("Transaction Failled: %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 
 	res := &pb.CreateTransactionResponse{
-		CreditWalletId: req.GetCreditWalletId(),
-		DebitWalletId:  req.GetDebitWalletId(),
-		Amount:         req.GetAmount(),
+		CreditWalletId: transaction.CreditWalletID,
+		DebitWalletId:  transaction.DebitWalletID,
+		Amount:         int32(transaction.Amount),
+		Id:             transaction.ID,
+		FeeAmount:      int32(transaction.FeeAmount),
+		FeeWalletId:    transaction.FeeWalletID,
+		Currency:       transaction.Currency,
+		DebitAmount:    int32(transaction.DebitAmount),
+		DebitCurrency:  transaction.DebitCurrency,
+		Rate:           transaction.Rate,
 	}
 
 	return res, nil
 }
 
+func (s *Server) ReverseTransaction(ctx context.Context,
+	req *pb.ReverseTransactionRequest) (*pb.ReverseTransactionResponse, error) {
+	reversal := &models.Reversal{
+		Amount:         int(req.GetAmount()),
+		RefundFee:      req.GetRefundFee(),
+		IdempotencyKey: req.GetIdempotencyKey(),
+	}
+
+	if reversal.Amount < 0 {
+		return nil, statusError(ctx, wallet.Invalid("invalid_amount", "amount must not be negative"))
+	}
+
+	transaction, err := s.service.ReverseTransaction(ctx, req.GetId(), reversal)
+	if err != nil {
+		log.Printf("Reversal Failled: %v\n", err)
+
+		return nil, statusError(ctx, err)
+	}
+
+	return &pb.ReverseTransactionResponse{Transaction: convertTransaction(transaction)}, nil
+}
+
 func (s *Server) GetWalletTransactionsByID(ctx context.Context,
 	req *pb.GetWalletTransactionsByIdRequest) (*pb.GetTransactionResponse, error) {
 	id := req.GetId()
 
-	transactions, err := s.service.GetWalletTransactionsByID(id)
+	page := models.Pagination{Offset: int(req.GetOffset()), Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+
+	filter, err := convertFilter(req.GetFilter())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	transactions, info, err := s.service.GetWalletTransactionsByID(ctx, id, filter, page)
 	if err != nil {
 		log.Printf("Unable to get transactions : %v\n", err)
 
-		return nil, errors.Wrap(err, "Error from db")
+		return nil, statusError(ctx, err)
 	}
 	var protoTransactions []*pb.Transaction
 
@@ -183,6 +311,8 @@ func (s *Server) GetWalletTransactionsByID(ctx context.Context,
 
 	res := &pb.GetTransactionResponse{
 		Transaction: protoTransactions,
+		Total:       int32(info.Total),
+		NextCursor:  info.NextCursor,
 	}
 
 	return res, nil
@@ -190,22 +320,68 @@ func (s *Server) GetWalletTransactionsByID(ctx context.Context,
 
 func convertTransaction(transaction *models.Transaction) *pb.Transaction {
 	return &pb.Transaction{
-		Id:             transaction.ID,
-		CreditWalletId: transaction.CreditWalletID,
-		DebitWalletId:  transaction.DebitWalletID,
-		Amount:         int32(transaction.Amount),
-		Type:           int32(transaction.Type),
-		FeeAmount:      int32(transaction.FeeAmount),
-		FeeWalletId:    transaction.FeeWalletID,
-		CreditUserId:   transaction.CreditUserID,
-		DebitUserId:    transaction.DebitUserID,
+		Id:                      transaction.ID,
+		CreditWalletId:          transaction.CreditWalletID,
+		DebitWalletId:           transaction.DebitWalletID,
+		Amount:                  int32(transaction.Amount),
+		Type:                    int32(transaction.Type),
+		FeeAmount:               int32(transaction.FeeAmount),
+		FeeWalletId:             transaction.FeeWalletID,
+		CreditUserId:            transaction.CreditUserID,
+		DebitUserId:             transaction.DebitUserID,
+		Currency:                transaction.Currency,
+		DebitAmount:             int32(transaction.DebitAmount),
+		DebitCurrency:           transaction.DebitCurrency,
+		Rate:                    transaction.Rate,
+		FeeWalletAmount:         int32(transaction.FeeWalletAmount),
+		FeeCurrency:             transaction.FeeCurrency,
+		OriginalTransactionId:   transaction.OriginalTransactionID,
+		RefundedAmount:          int32(transaction.RefundedAmount),
+		RefundedFeeAmount:       int32(transaction.RefundedFeeAmount),
+		RefundedFeeWalletAmount: int32(transaction.RefundedFeeWalletAmount),
 	}
 }
 
+// convertFilter reads the transaction filter of a list request, a missing filter matches everything.
+func convertFilter(f *pb.TransactionFilter) (models.TransactionFilter, error) {
+	filter := models.TransactionFilter{
+		MinAmount:            int(f.GetMinAmount()),
+		MaxAmount:            int(f.GetMaxAmount()),
+		Direction:            f.GetDirection(),
+		CounterpartyWalletID: f.GetCounterpartyWalletId(),
+		CounterpartyUserID:   f.GetCounterpartyUserId(),
+		FeeWalletID:          f.GetFeeWalletId(),
+	}
+
+	var err error
+
+	if from := f.GetDateFrom(); from != "" {
+		filter.DateFrom, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, wallet.Invalid("invalid_filter", "dateFrom must be an RFC 3339 timestamp")
+		}
+	}
+
+	if to := f.GetDateTo(); to != "" {
+		filter.DateTo, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, wallet.Invalid("invalid_filter", "dateTo must be an RFC 3339 timestamp")
+		}
+	}
+
+	if f != nil && f.Type != nil {
+		priority := int(f.GetType())
+		filter.Type = &priority
+	}
+
+	return filter, nil
+}
+
 func convertUser(user *models.User) *pb.User {
 	return &pb.User{
-		Id:    user.ID,
-		Token: *user.Token, //convertNullStr
+		Id:   user.ID,
+		Name: user.Name,
+		Role: user.Role,
 	}
 }
 
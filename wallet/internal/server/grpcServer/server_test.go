@@ -0,0 +1,89 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/workshops/wallet/internal/proto"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/services/rate"
+	"github.com/workshops/wallet/internal/services/validator"
+	"github.com/workshops/wallet/internal/services/wallet"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestServer() *Server {
+	service := wallet.NewService(memory.NewRepository(), nil, rate.NewStaticProvider("USD", nil),
+		wallet.QueueConfig{}, wallet.Timeouts{})
+
+	return NewGrpcServer(service, "postgre", newTestWrapper(), validator.NewValidator())
+}
+
+// violations returns the fields named by the BadRequest detail of err.
+func violations(t *testing.T, err error) []string {
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+
+	return fields
+}
+
+//nolint
+func TestCreateUserValidation(t *testing.T) {
+	srv := newTestServer()
+
+	_, err := srv.CreateUser(context.Background(), &pb.CreateUserRequest{Name: string(make([]byte, 65)), Password: "password123"})
+	assert.Equal(t, []string{"Name"}, violations(t, err))
+
+	_, err = srv.CreateUser(context.Background(), &pb.CreateUserRequest{Name: "alice"})
+	assert.Equal(t, []string{"Password"}, violations(t, err))
+
+	res, err := srv.CreateUser(context.Background(), &pb.CreateUserRequest{Name: "alice", Password: "password123"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, res.GetId())
+}
+
+//nolint
+func TestCreateWallet(t *testing.T) {
+	srv := newTestServer()
+	ctx := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "user-1", Role: "user"})
+
+	_, err := srv.CreateWallet(ctx, &pb.CreateWalletRequest{Balance: 100, UserId: "user-1", Currency: "dollars"})
+	assert.Equal(t, []string{"Currency"}, violations(t, err))
+
+	res, err := srv.CreateWallet(ctx, &pb.CreateWalletRequest{Balance: 100, UserId: "user-1", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetId())
+
+	found, err := srv.GetWalletByID(ctx, &pb.GetWalledByIdRequest{Id: res.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", found.GetWallet().GetCurrency())
+	assert.Equal(t, int32(100), found.GetWallet().GetBalance())
+}
+
+//nolint
+func TestCreateTransactionValidation(t *testing.T) {
+	srv := newTestServer()
+	ctx := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "user-1", Role: "user"})
+
+	for _, amount := range []int32{0, -500} {
+		_, err := srv.CreateTransaction(ctx, &pb.CreateTransactionRequest{CreditWalletId: "w1", DebitWalletId: "w2", Amount: amount})
+		assert.Equal(t, []string{"Amount"}, violations(t, err))
+	}
+
+	_, err := srv.CreateTransaction(ctx, &pb.CreateTransactionRequest{DebitWalletId: "w2", Amount: 100,
+		IdempotencyKey: string(make([]byte, 256))})
+	assert.Equal(t, []string{"CreditWalletID", "IdempotencyKey"}, violations(t, err))
+}
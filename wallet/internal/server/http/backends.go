@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/workshops/wallet/internal/middleware/auth"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+// Backends is the registry of storage backends, each served under its name as the {db} prefix of every route.
+type Backends struct {
+	services map[string]*wallet.Service
+}
+
+func NewBackends() *Backends {
+	return &Backends{services: make(map[string]*wallet.Service)}
+}
+
+// Register serves service under /name. A later registration of the same name replaces the earlier one.
+func (b *Backends) Register(name string, service *wallet.Service) *Backends {
+	b.services[name] = service
+	return b
+}
+
+// Lookup returns the service registered under name.
+func (b *Backends) Lookup(name string) (*wallet.Service, bool) {
+	service, ok := b.services[name]
+	return service, ok
+}
+
+// backend resolves the {db} segment of the request. For an unknown name it replies
+// with a 404 error and reports false.
+func (s *Server) backend(w http.ResponseWriter, r *http.Request) (*wallet.Service, bool) {
+	service, err := s.lookup(r)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+
+	return service, true
+}
+
+// tokenBackend is the backend the auth middleware checks the tokens of the request against: its
+// name is the audience of the tokens it issues and its sessions tell the revoked ones.
+func (s *Server) tokenBackend(r *http.Request) (string, auth.Revocations, error) {
+	service, err := s.lookup(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return mux.Vars(r)["db"], service, nil
+}
+
+func (s *Server) lookup(r *http.Request) (*wallet.Service, error) {
+	db := mux.Vars(r)["db"]
+
+	service, ok := s.backends.Lookup(db)
+	if !ok {
+		return nil, &wallet.Error{Kind: wallet.KindNotFound, Code: "unknown_backend", Message: "unknown backend: " + db}
+	}
+
+	return service, nil
+}
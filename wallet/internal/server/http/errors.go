@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+var (
+	errInvalidPage   = wallet.Invalid("invalid_page", "offset must be a non negative and limit a positive number")
+	errInvalidFilter = wallet.Invalid("invalid_filter", "dates must be RFC 3339 timestamps, amounts and type numbers")
+	errRouteNotFound = &wallet.Error{Kind: wallet.KindNotFound, Code: "route_not_found", Message: "no such route"}
+)
+
+// writeError replies with the swagger error object of err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apierror.Write(w, r, err)
+}
+
+// invalidBody reports a request body that cannot be decoded.
+func invalidBody(err error) error {
+	return &wallet.Error{Kind: wallet.KindInvalid, Code: "invalid_body", Message: "request body is not valid JSON", Err: err}
+}
+
+// invalidInput reports a request that fails validation. The validator names the offending fields.
+func invalidInput(err error) error {
+	return &wallet.Error{Kind: wallet.KindInvalid, Code: "validation_failed", Message: err.Error(), Err: err}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, errRouteNotFound)
+}
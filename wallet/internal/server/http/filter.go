@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// transactionFilter reads the filter query parameters of a transaction list request.
+// Dates are RFC 3339 timestamps.
+func transactionFilter(r *http.Request) (models.TransactionFilter, error) {
+	var (
+		filter models.TransactionFilter
+		err    error
+	)
+
+	query := r.URL.Query()
+
+	if from := query.Get("dateFrom"); from != "" {
+		filter.DateFrom, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, errInvalidFilter
+		}
+	}
+
+	if to := query.Get("dateTo"); to != "" {
+		filter.DateTo, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, errInvalidFilter
+		}
+	}
+
+	if min := query.Get("minAmount"); min != "" {
+		filter.MinAmount, err = strconv.Atoi(min)
+		if err != nil {
+			return filter, errInvalidFilter
+		}
+	}
+
+	if max := query.Get("maxAmount"); max != "" {
+		filter.MaxAmount, err = strconv.Atoi(max)
+		if err != nil {
+			return filter, errInvalidFilter
+		}
+	}
+
+	if t := query.Get("type"); t != "" {
+		priority, err := strconv.Atoi(t)
+		if err != nil {
+			return filter, errInvalidFilter
+		}
+
+		filter.Type = &priority
+	}
+
+	filter.Direction = query.Get("direction")
+	filter.CounterpartyWalletID = query.Get("counterpartyWalletId")
+	filter.CounterpartyUserID = query.Get("counterpartyUserId")
+	filter.FeeWalletID = query.Get("feeWalletId")
+
+	return filter, nil
+}
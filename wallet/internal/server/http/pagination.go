@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// pagination reads the offset, limit and cursor query parameters of a list request.
+func pagination(r *http.Request) (models.Pagination, error) {
+	var (
+		page models.Pagination
+		err  error
+	)
+
+	query := r.URL.Query()
+
+	if offset := query.Get("offset"); offset != "" {
+		page.Offset, err = strconv.Atoi(offset)
+		if err != nil || page.Offset < 0 {
+			return page, errInvalidPage
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil || page.Limit < 1 {
+			return page, errInvalidPage
+		}
+	}
+
+	page.Cursor = query.Get("cursor")
+
+	return page, nil
+}
+
+// writePageInfo reports the total count and the cursor of the next page in the response headers.
+func writePageInfo(w http.ResponseWriter, info *models.PageInfo) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(info.Total))
+
+	if info.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", info.NextCursor)
+	}
+}
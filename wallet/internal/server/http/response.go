@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// ndjson is the media type of streamed lists, one JSON document per line.
+const ndjson = "application/x-ndjson"
+
+// listPage is the envelope of a page of a list.
+type listPage struct {
+	Items interface{} `json:"items"`
+	*models.PageInfo
+}
+
+// writeJSON replies with v as a single JSON document.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Unable to encode response: %v\n", err)
+	}
+}
+
+// writePage replies with the items of a page in the envelope. The page info is repeated in the headers.
+func writePage(w http.ResponseWriter, items interface{}, info *models.PageInfo) {
+	writePageInfo(w, info)
+	writeJSON(w, http.StatusOK, listPage{Items: items, PageInfo: info})
+}
+
+// wantsStream reports whether the client accepts a streamed list.
+func wantsStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == ndjson {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stream writes a list as NDJSON. Each item is flushed as soon as it is written, so the client
+// gets the rows as the repository reads them and nothing is buffered.
+type stream struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	encoder *json.Encoder
+	started bool
+}
+
+func newStream(w http.ResponseWriter, r *http.Request) *stream {
+	return &stream{w: w, r: r, encoder: json.NewEncoder(w)}
+}
+
+func (s *stream) start() {
+	s.w.Header().Set("Content-Type", ndjson)
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+// write sends one item. It fails when the client is gone, which stops the repository.
+func (s *stream) write(v interface{}) error {
+	if !s.started {
+		s.start()
+	}
+
+	err := s.encoder.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// close ends the stream with the result of the repository. An error before the first item is an
+// ordinary error response. After it the status is already sent, so the connection is cut instead,
+// which tells the client that the list is incomplete.
+func (s *stream) close(err error) {
+	switch {
+	case err == nil && !s.started:
+		s.start()
+	case err == nil:
+	case !s.started:
+		writeError(s.w, s.r, err)
+	default:
+		log.Printf("%s %s stream failed (request %s): %v\n", s.r.Method, s.r.URL.Path,
+			apierror.RequestIDFrom(s.r.Context()), err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -1,27 +1,47 @@
 package http
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
+	"github.com/workshops/wallet/internal/middleware/apierror"
+	"github.com/workshops/wallet/internal/middleware/auth"
 )
 
 // will hold http routes and will registrate them.
 func NewRouter(s *Server) *mux.Router {
 	r := mux.NewRouter()
-	r.HandleFunc("/{db}/users", s.GetUsers).Methods("GET")
+	r.Use(apierror.RequestID)
+	r.NotFoundHandler = apierror.RequestID(http.HandlerFunc(notFound))
+	r.HandleFunc("/rates", s.GetRates).Methods("GET")
+	r.HandleFunc("/.well-known/jwks.json", s.GetJWKS).Methods("GET")
+	r.Handle("/{db}/users", s.restrict(auth.Staff, s.GetUsers)).Methods("GET")
 	r.HandleFunc("/{db}/users", s.CreateUser).Methods("POST")
+	r.Handle("/{db}/users/{id}/role", s.restrict(auth.Admin, s.SetUserRole)).Methods("PUT")
+	r.HandleFunc("/{db}/auth/login", s.Login).Methods("POST")
+	r.HandleFunc("/{db}/auth/refresh", s.Refresh).Methods("POST")
+	r.HandleFunc("/{db}/auth/logout", s.Logout).Methods("POST")
 
 	sec := r.PathPrefix("/{db}/wallets").Subrouter()
-	sec.Use(s.jwtWrapper.AuthMiddleware)
+	sec.Use(s.jwtWrapper.AuthMiddleware(s.tokenBackend))
 	sec.HandleFunc("", s.CreateWallet).Methods("POST")
 	sec.HandleFunc("/{id}", s.GetWalletByID).Methods("GET")
 	sec.HandleFunc("/{id}/transactions", s.GetWalletTransactionsByID).Methods("GET")
+	sec.HandleFunc("/{id}/ledger", s.GetWalletLedger).Methods("GET")
 
 	trn := r.PathPrefix("/{db}/transactions").Subrouter()
-	trn.Use(s.jwtWrapper.AuthMiddleware)
+	trn.Use(s.jwtWrapper.AuthMiddleware(s.tokenBackend))
 	trn.HandleFunc("", s.GetTransactions).Methods("GET")
 	trn.HandleFunc("", s.CreateTransactions).Methods("PUT")
+	trn.HandleFunc("/queue", s.GetQueueDepth).Methods("GET")
+	trn.Handle("/{id}/reverse", auth.Require(auth.Staff)(http.HandlerFunc(s.ReverseTransaction))).Methods("POST")
 	trn.HandleFunc("/day/{id}", s.GetWalletAmountDayByID).Methods("GET")
 	trn.HandleFunc("/week/{id}", s.GetWalletAmountWeekByID).Methods("GET")
 
 	return r
 }
+
+// restrict serves the handler to the callers the policy lets in, for routes outside the authenticated subrouters.
+func (s *Server) restrict(policy auth.Policy, handler http.HandlerFunc) http.Handler {
+	return s.jwtWrapper.AuthMiddleware(s.tokenBackend)(auth.Require(policy)(handler))
+}
@@ -2,8 +2,8 @@ package http
 
 import (
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -16,400 +16,503 @@ type Validator interface {
 	Validate(interface{}) error
 }
 
+type RateProvider interface {
+	Rates() ([]*models.Rate, error)
+}
+
 type Server struct {
-	valid          Validator
-	jwtWrapper     *auth.JwtWrapper
-	servicePostgre *wallet.Service
-	serviceMongo   *wallet.Service
+	valid      Validator
+	rates      RateProvider
+	jwtWrapper *auth.JwtWrapper
+	backends   *Backends
 }
 
-func NewServer(servicePostgre *wallet.Service, serviceMongo *wallet.Service, jwtWrapper *auth.JwtWrapper, validator Validator) *Server {
+func NewServer(backends *Backends, rates RateProvider, jwtWrapper *auth.JwtWrapper, validator Validator) *Server {
 	return &Server{
-		servicePostgre: servicePostgre,
-		serviceMongo:   serviceMongo,
-		jwtWrapper:     jwtWrapper,
-		valid:          validator,
+		backends:   backends,
+		rates:      rates,
+		jwtWrapper: jwtWrapper,
+		valid:      validator,
 	}
 }
 
-func (s *Server) RunServer() {
-	router := NewRouter(s)
-	addr := "localhost:8090"
-
-	log.Printf("start HTTP server at %s", addr)
-	log.Fatal(http.ListenAndServe(addr, router))
+// refreshRequest is the body of a refresh or a logout.
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
 }
 
 func (s *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	credentials, ok := s.credentials(w, r)
+	if !ok {
+		return
+	}
 
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	user, err := service.Register(r.Context(), credentials)
 	if err != nil {
-		log.Printf("Unable to get user from request: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// Login exchanges the name and password of a user for an access and a refresh token.
+func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	credentials, ok := s.credentials(w, r)
+	if !ok {
+		return
 	}
 
-	err = s.valid.Validate(user)
+	user, err := service.Authenticate(r.Context(), credentials)
 	if err != nil {
-		log.Printf("Bad input: %v\n", err)
+		writeError(w, r, err)
 		return
 	}
 
-	token, err := s.jwtWrapper.GenerateToken(user.Name)
+	session, err := service.StartSession(r.Context(), user, s.jwtWrapper.RefreshTTL)
 	if err != nil {
-		log.Printf("Unable to generate token: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeTokens(w, r, session)
+}
+
+// Refresh exchanges a refresh token for new tokens. The refresh token cannot be used again.
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
 		return
 	}
 
-	switch db {
-	case "mongo":
-		err = s.serviceMongo.CreateUser(token)
-		if err != nil {
-			http.Error(w, "Unable to create user", http.StatusForbidden)
-			log.Printf("Unable to create: %v\n", err)
-			return
-		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Created user: " + user.Name))
-	case "postgre":
-		err = s.servicePostgre.CreateUser(token)
-		if err != nil {
-			http.Error(w, "Unable to create user", http.StatusForbidden)
-			log.Printf("Unable to create: %v\n", err)
-			return
-		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Created user: " + user.Name))
-	default:
-		w.Write([]byte("invalid db"))
+	body, ok := s.refreshToken(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := service.RefreshSession(r.Context(), body.RefreshToken, s.jwtWrapper.RefreshTTL)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	s.writeTokens(w, r, session)
+}
+
+// Logout revokes the refresh token and every access token of its session.
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := s.refreshToken(w, r)
+	if !ok {
+		return
+	}
+
+	err := service.EndSession(r.Context(), body.RefreshToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTokens answers with the tokens of the session, whose access token only the backend of
+// the request accepts.
+func (s *Server) writeTokens(w http.ResponseWriter, r *http.Request, session *wallet.Session) {
+	tokens, err := s.jwtWrapper.SessionTokens(session, mux.Vars(r)["db"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// refreshToken decodes and validates the body of a refresh or a logout.
+func (s *Server) refreshToken(w http.ResponseWriter, r *http.Request) (refreshRequest, bool) {
+	var body refreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, r, invalidBody(err))
+		return body, false
+	}
+
+	err = s.valid.Validate(body)
+	if err != nil {
+		writeError(w, r, invalidInput(err))
+		return body, false
+	}
+
+	return body, true
+}
+
+// credentials decodes and validates the body of a registration or a login.
+func (s *Server) credentials(w http.ResponseWriter, r *http.Request) (models.Credentials, bool) {
+	var credentials models.Credentials
+
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		writeError(w, r, invalidBody(err))
+		return credentials, false
+	}
+
+	err = s.valid.Validate(credentials)
+	if err != nil {
+		writeError(w, r, invalidInput(err))
+		return credentials, false
+	}
+
+	return credentials, true
 }
 
 func (s *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
-
-	switch db {
-	case "mongo":
-		users, err := s.serviceMongo.GetUsers()
-		if err != nil {
-			http.Error(w, "Unable to get users", http.StatusForbidden)
-			log.Printf("Unable to get users : %v\n", err)
-		}
-
-		for _, user := range users {
-			err = json.NewEncoder(w).Encode(user)
-			if err != nil {
-				log.Printf("Unable to encode user: %v\n", err)
-				return
-			}
-		}
-	case "postgre":
-		users, err := s.servicePostgre.GetUsers()
-		if err != nil {
-			http.Error(w, "Unable to get users", http.StatusForbidden)
-			log.Printf("Unable to get users : %v\n", err)
-		}
-
-		for _, user := range users {
-			err = json.NewEncoder(w).Encode(user)
-			if err != nil {
-				log.Printf("Unable to encode user: %v\n", err)
-				return
-			}
-		}
-	default:
-		w.Write([]byte("invalid db"))
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pagination(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wantsStream(r) {
+		out := newStream(w, r)
+		out.close(service.StreamUsers(r.Context(), page, func(user *models.User) error {
+			return out.write(user)
+		}))
+
+		return
+	}
+
+	users, info, err := service.GetUsers(r.Context(), page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, users, info)
+}
+
+// SetUserRole changes the role of a user. The user's tokens keep the old role until they are refreshed.
+func (s *Server) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	var change models.RoleChange
+
+	err := json.NewDecoder(r.Body).Decode(&change)
+	if err != nil {
+		writeError(w, r, invalidBody(err))
+		return
 	}
+
+	err = s.valid.Validate(change)
+	if err != nil {
+		writeError(w, r, invalidInput(err))
+		return
+	}
+
+	user, err := service.SetUserRole(r.Context(), mux.Vars(r)["id"], change.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
 }
 
 func (s *Server) CreateWallet(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
 	var wallet models.Wallet
 	err := json.NewDecoder(r.Body).Decode(&wallet)
 	if err != nil {
-		log.Printf("Unable to get wallet from request: %v\n", err)
+		writeError(w, r, invalidBody(err))
+		return
 	}
 
 	err = s.valid.Validate(wallet)
 	if err != nil {
-		log.Printf("Bad input: %v\n", err)
+		writeError(w, r, invalidInput(err))
 		return
 	}
 
-	switch db {
-	case "mongo":
-		err = s.serviceMongo.CreateWallet(&wallet)
-		if err != nil {
-			log.Printf("Unable to create: %v\n", err)
-			return
-		}
-
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(wallet)
-	case "postgre":
-		err = s.servicePostgre.CreateWallet(&wallet)
-		if err != nil {
-			log.Printf("Unable to create: %v\n", err)
-			return
-		}
-
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(wallet)
-	default:
-		w.Write([]byte("invalid db"))
+	err = service.CreateWallet(r.Context(), &wallet)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusCreated, wallet)
 }
 
 func (s *Server) GetWalletByID(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
-	params = mux.Vars(r)
+	params := mux.Vars(r)
 	id := params["id"]
 
-	switch db {
-	case "mongo":
-		wallet, err := s.serviceMongo.GetWalletByID(id)
-		if err != nil {
-			http.Error(w, "Unable to get wallet", http.StatusForbidden)
-			log.Printf("Unable to get wallet: %v\n", err)
-			return
-		}
-
-		err = json.NewEncoder(w).Encode(wallet)
-		if err != nil {
-			log.Printf("Unable to encode wallet: %v\n", err)
-			return
-		}
-	case "postgre":
-		wallet, err := s.servicePostgre.GetWalletByID(id)
-		if err != nil {
-			http.Error(w, "Unable to get wallet", http.StatusForbidden)
-			log.Printf("Unable to get wallet: %v\n", err)
-			return
-		}
-
-		err = json.NewEncoder(w).Encode(wallet)
-		if err != nil {
-			log.Printf("Unable to encode wallet: %v\n", err)
-			return
-		}
-	default:
-		w.Write([]byte("invalid db"))
+	wallet, err := service.GetWalletByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, wallet)
 }
 
 func (s *Server) GetWalletTransactionsByID(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
-	params = mux.Vars(r)
-	id := params["id"]
+	id := mux.Vars(r)["id"]
+
+	filter, err := transactionFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := pagination(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wantsStream(r) {
+		out := newStream(w, r)
+		out.close(service.StreamWalletTransactionsByID(r.Context(), id, filter, page,
+			func(transaction *models.Transaction) error {
+				return out.write(transaction)
+			}))
+
+		return
+	}
 
-	switch db {
-	case "mongo":
-		transactions, err := s.serviceMongo.GetWalletTransactionsByID(id)
-		if err != nil {
-			http.Error(w, "Unable to get wallet transactions", http.StatusForbidden)
-			log.Printf("Unable to get transactions : %v\n", err)
-		}
-
-		for _, transaction := range transactions {
-			err = json.NewEncoder(w).Encode(transaction)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	case "postgre":
-		transactions, err := s.servicePostgre.GetWalletTransactionsByID(id)
-		if err != nil {
-			http.Error(w, "Unable to get wallet transactions", http.StatusForbidden)
-			log.Printf("Unable to get transactions : %v\n", err)
-		}
-
-		for _, transaction := range transactions {
-			err = json.NewEncoder(w).Encode(transaction)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	default:
-		w.Write([]byte("invalid db"))
+	transactions, info, err := service.GetWalletTransactionsByID(r.Context(), id, filter, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	writePage(w, transactions, info)
 }
 
 func (s *Server) GetTransactions(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
-
-	switch db {
-	case "mongo":
-		transactions, err := s.serviceMongo.GetTransactions()
-		if err != nil {
-			http.Error(w, "Unable to get transactions", http.StatusForbidden)
-			log.Printf("Unable to get transactions : %v\n", err)
-		}
-
-		for _, transaction := range transactions {
-			err = json.NewEncoder(w).Encode(transaction)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	case "postgre":
-		transactions, err := s.servicePostgre.GetTransactions()
-		if err != nil {
-			http.Error(w, "Unable to get transactions", http.StatusForbidden)
-			log.Printf("Unable to get transactions : %v\n", err)
-		}
-
-		for _, transaction := range transactions {
-			err = json.NewEncoder(w).Encode(transaction)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	default:
-		w.Write([]byte("invalid db"))
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
 	}
+
+	filter, err := transactionFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := pagination(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wantsStream(r) {
+		out := newStream(w, r)
+		out.close(service.StreamTransactions(r.Context(), filter, page, func(transaction *models.Transaction) error {
+			return out.write(transaction)
+		}))
+
+		return
+	}
+
+	transactions, info, err := service.GetTransactions(r.Context(), filter, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, transactions, info)
 }
 
 func (s *Server) CreateTransactions(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
 	var transaction models.Transaction
 	err := json.NewDecoder(r.Body).Decode(&transaction)
 	if err != nil {
-		log.Printf("Unable to get transaction from request: %v\n", err)
+		writeError(w, r, invalidBody(err))
+		return
+	}
+
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		transaction.IdempotencyKey = key
 	}
 
 	err = s.valid.Validate(transaction)
 	if err != nil {
-		log.Printf("Bad input: %v\n", err)
+		writeError(w, r, invalidInput(err))
 		return
 	}
 
-	switch db {
-	case "mongo":
-		err = s.serviceMongo.CreateTransaction(&transaction)
-		if err != nil {
-			http.Error(w, "Unable to create transaction", http.StatusForbidden)
-			log.Printf("Transaction Failled: %v\n", err)
-			return
-		}
+	err = service.CreateTransaction(r.Context(), &transaction)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-		json.NewEncoder(w).Encode(transaction)
-	case "postgre":
-		err = s.servicePostgre.CreateTransaction(&transaction)
-		if err != nil {
-			http.Error(w, "Unable to create transaction", http.StatusForbidden)
-			log.Printf("Transaction Failled: %v\n", err)
-			return
-		}
+	writeJSON(w, http.StatusOK, transaction)
+}
 
-		json.NewEncoder(w).Encode(transaction)
-	default:
-		w.Write([]byte("invalid db"))
+func (s *Server) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
 	}
+
+	id := mux.Vars(r)["id"]
+
+	// An empty body asks for a full reversal without refunding the fee.
+	var reversal models.Reversal
+	err := json.NewDecoder(r.Body).Decode(&reversal)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, invalidBody(err))
+		return
+	}
+
+	reversal.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+	err = s.valid.Validate(reversal)
+	if err != nil {
+		writeError(w, r, invalidInput(err))
+		return
+	}
+
+	transaction, err := service.ReverseTransaction(r.Context(), id, &reversal)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transaction)
 }
 
 func (s *Server) GetWalletAmountDayByID(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
-	params = mux.Vars(r)
-	id := params["id"]
+	id := mux.Vars(r)["id"]
 
 	var day models.Week
 	err := json.NewDecoder(r.Body).Decode(&day)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, invalidBody(err))
+		return
+	}
+
+	days, err := service.GetWalletAmountDayByID(r.Context(), id, day)
 	if err != nil {
-		log.Printf("Unable to get day from request: %v\n", err)
-	}
-
-	switch db {
-	case "mongo":
-		days, err := s.serviceMongo.GetWalletAmountDayByID(id, day)
-		if err != nil {
-			http.Error(w, "Unable to get amount per day:", http.StatusForbidden)
-			log.Printf("Unable to get amount per day:: %v\n", err)
-			return
-		}
-
-		for _, day := range days {
-			err = json.NewEncoder(w).Encode(day)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	case "postgre":
-		days, err := s.servicePostgre.GetWalletAmountDayByID(id, day)
-		if err != nil {
-			http.Error(w, "Unable to get amount per day", http.StatusForbidden)
-			log.Printf("Unable to get amount per day: %v\n", err)
-			return
-		}
-
-		for _, day := range days {
-			err = json.NewEncoder(w).Encode(day)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	default:
-		w.Write([]byte("invalid db"))
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, days)
 }
 
 func (s *Server) GetWalletAmountWeekByID(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	db := params["db"]
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
 
-	params = mux.Vars(r)
-	id := params["id"]
+	id := mux.Vars(r)["id"]
 
 	var day models.Week
 	err := json.NewDecoder(r.Body).Decode(&day)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, invalidBody(err))
+		return
+	}
+
+	days, err := service.GetWalletAmountWeekByID(r.Context(), id, day)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, days)
+}
+
+func (s *Server) GetRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.rates.Rates()
 	if err != nil {
-		log.Printf("Unable to get day from request: %v\n", err)
-	}
-
-	switch db {
-	case "mongo":
-		days, err := s.servicePostgre.GetWalletAmountWeekByID(id, day)
-		if err != nil {
-			http.Error(w, "Unable to get amount per day:", http.StatusForbidden)
-			log.Printf("Unable to get amount per day:: %v\n", err)
-			return
-		}
-
-		w.Write([]byte(fmt.Sprintf("Amount:%v", days)))
-	case "postgre":
-		days, err := s.servicePostgre.GetWalletAmountWeekByID(id, day)
-		if err != nil {
-			http.Error(w, "Unable to get amount per day", http.StatusForbidden)
-			log.Printf("Unable to get amount per day: %v\n", err)
-			return
-		}
-
-		for _, day := range days {
-			err = json.NewEncoder(w).Encode(day)
-			if err != nil {
-				log.Printf("Unable to encode transaction: %v\n", err)
-				return
-			}
-		}
-	default:
-		w.Write([]byte("invalid db"))
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, rates)
+}
+
+// GetJWKS publishes the public keys access tokens are verified with. Clients may cache it
+// for five minutes, so a new key is listed as a verification key before it signs.
+func (s *Server) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, s.jwtWrapper.Keys.JWKS())
+}
+
+func (s *Server) GetQueueDepth(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, service.QueueDepth())
+}
+
+func (s *Server) GetWalletLedger(w http.ResponseWriter, r *http.Request) {
+	service, ok := s.backend(w, r)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	entries, err := service.GetWalletLedger(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
 }
@@ -1,32 +1,425 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/config"
 	"github.com/workshops/wallet/internal/middleware/auth"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
 	"github.com/workshops/wallet/internal/repository/postgre"
+	"github.com/workshops/wallet/internal/services/fee"
+	"github.com/workshops/wallet/internal/services/rate"
 	"github.com/workshops/wallet/internal/services/validator"
 	"github.com/workshops/wallet/internal/services/wallet"
 )
 
 //nolint
 func TestGetUsers(t *testing.T) {
-	db, _, err := sqlmock.New()
+	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatal("Unable to connect")
 	}
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 	validate := validator.NewValidator()
-	service := wallet.NewService(repo)
-	wrapper := auth.NewJwtWrapper("verysecretkey", 999)
-	srv := NewServer(service, wrapper, validate)
-	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	fees, err := fee.NewPolicy(&config.Fee{Percent: 1.5, WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := rate.NewStaticProvider("USD", nil)
+	service := wallet.NewService(repo, fees, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	srv := NewServer(NewBackends().Register("postgre", service), rates, wrapper, validate)
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT id,name,role FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}))
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/postgre/users", nil), map[string]string{"db": "postgre"})
 	w := httptest.NewRecorder()
 	srv.GetUsers(w, req)
 	assert.Equal(t, w.Code, 200)
+	assert.JSONEq(t, `{"items":[],"total":0}`, w.Body.String())
+}
+
+//nolint
+func TestListResponses(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	service := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if _, err := service.Register(context.Background(), models.Credentials{Name: name, Password: "password123"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := NewRouter(NewServer(NewBackends().Register("memory", service), rates, wrapper, validator.NewValidator()))
+	token, err := wrapper.GenerateToken("928eeecf-05ad-4e6f-ab7f-5477225b4c52", "support", models.RoleSupport, "jti-1", "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/memory/users?limit=2", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var page struct {
+		Items      []models.User `json:"items"`
+		Total      int           `json:"total"`
+		NextCursor string        `json:"nextCursor"`
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, 3, page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	req = httptest.NewRequest(http.MethodGet, "/memory/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/x-ndjson; q=1.0, application/json; q=0.5")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		var user models.User
+		assert.NoError(t, json.Unmarshal([]byte(line), &user))
+		assert.NotEmpty(t, user.Name)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/memory/users?cursor=%21", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+//nolint
+func TestAccounts(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	service := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	router := NewRouter(NewServer(NewBackends().Register("memory", service), rates, wrapper, validator.NewValidator()))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("/memory/users", `{"name":"serhii","password":"correct horse"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+	var user models.User
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&user))
+	assert.NotEmpty(t, user.ID)
+	assert.Equal(t, "serhii", user.Name)
+
+	w = post("/memory/users", `{"name":"serhii","password":"battery staple"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_exists"`)
+
+	w = post("/memory/auth/login", `{"name":"serhii","password":"correct horse"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	claims, err := wrapper.ValidateToken(login.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "serhii", claims.Name)
+
+	w = post("/memory/auth/login", `{"name":"serhii","password":"battery staple"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"invalid_credentials"`)
+
+	w = post("/memory/auth/login", `{"name":"serhii"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+//nolint
+func TestSessions(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	service := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	other := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	backends := NewBackends().Register("memory", service).Register("other", other)
+	router := NewRouter(NewServer(backends, rates, wrapper, validator.NewValidator()))
+	if _, err := service.Register(context.Background(), models.Credentials{Name: "serhii", Password: "correct horse"}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+	getWalletOf := func(db, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+db+"/wallets/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	getWallet := func(token string) *httptest.ResponseRecorder {
+		return getWalletOf("memory", token)
+	}
+
+	var login auth.Tokens
+	w := post("/memory/auth/login", `{"name":"serhii","password":"correct horse"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.NotEmpty(t, login.RefreshToken)
+	assert.Equal(t, int64(3600), login.ExpiresIn)
+
+	var refreshed auth.Tokens
+	w = post("/memory/auth/refresh", `{"refreshToken":"`+login.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&refreshed))
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, http.StatusNotFound, getWallet(refreshed.Token).Code, "a valid token gets past the middleware")
+
+	// The other backend cannot tell whether the session has ended, so it refuses the token.
+	w = getWalletOf("other", refreshed.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "token is issued for another backend")
+
+	w = post("/memory/auth/logout", `{"refreshToken":"`+refreshed.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = getWallet(refreshed.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "token is revoked")
+	assert.Equal(t, http.StatusUnauthorized, getWallet(login.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, getWalletOf("other", refreshed.Token).Code)
+
+	w = post("/memory/auth/refresh", `{"refreshToken":"`+refreshed.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"invalid_refresh_token"`)
+
+	w = post("/memory/auth/refresh", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+//nolint
+func TestWalletOwnership(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	fees, err := fee.NewPolicy(&config.Fee{Percent: 1.5, WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := memory.NewRepository(&models.Wallet{ID: "85aa7525-4fdb-4436-a600-66ffc55e0f65", UserID: "system", Currency: "USD"})
+	service := wallet.NewService(repo, fees, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	router := NewRouter(NewServer(NewBackends().Register("memory", service), rates, wrapper, validator.NewValidator()))
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tokens := make(map[string]string)
+	users := make(map[string]string)
+	for _, name := range []string{"alice", "bob"} {
+		user, err := service.Register(context.Background(), models.Credentials{Name: name, Password: "password123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+		tokens[name], err = wrapper.GenerateToken(user.ID, name, models.RoleUser, name+"-session", "memory")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := do(http.MethodPost, "/memory/wallets", tokens["alice"], `{"userId":"`+users["bob"]+`","balance":100}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"forbidden"`)
+
+	w = do(http.MethodPost, "/memory/wallets", tokens["alice"], `{"userId":"`+users["alice"]+`","balance":100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var created models.Wallet
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+
+	w = do(http.MethodGet, "/memory/wallets/"+created.ID, tokens["alice"], "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	w = do(http.MethodGet, "/memory/wallets/"+created.ID, tokens["bob"], "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do(http.MethodPost, "/memory/wallets", tokens["bob"], `{"userId":"`+users["bob"]+`","balance":100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var bobs models.Wallet
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bobs))
+
+	w = do(http.MethodPut, "/memory/transactions", tokens["alice"],
+		`{"creditWalletId":"`+created.ID+`","debitWalletId":"`+bobs.ID+`","amount":10}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var transaction models.Transaction
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&transaction))
+	assert.NotEmpty(t, transaction.ID)
+}
+
+//nolint
+func TestRoles(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	service := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	router := NewRouter(NewServer(NewBackends().Register("memory", service), rates, wrapper, validator.NewValidator()))
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(name string) (string, string) {
+		w := do(http.MethodPost, "/memory/auth/login", "", `{"name":"`+name+`","password":"password123"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var tokens auth.Tokens
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&tokens))
+		return tokens.Token, tokens.RefreshToken
+	}
+
+	if err := service.BootstrapAdmin(context.Background(), models.Credentials{Name: "root", Password: "password123"}); err != nil {
+		t.Fatal(err)
+	}
+	alice, err := service.Register(context.Background(), models.Credentials{Name: "alice", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := login("root")
+	user, refresh := login("alice")
+
+	w := do(http.MethodGet, "/memory/users", user, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"forbidden"`)
+	w = do(http.MethodPost, "/memory/transactions/1/reverse", user, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = do(http.MethodPut, "/memory/users/"+alice.ID+"/role", user, `{"role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do(http.MethodGet, "/memory/users", root, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do(http.MethodPut, "/memory/users/"+alice.ID+"/role", root, `{"role":"superuser"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPut, "/memory/users/"+alice.ID+"/role", root, `{"role":"support"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"support"`)
+
+	// The new role is in the tokens issued from the next refresh on.
+	w = do(http.MethodPost, "/memory/auth/refresh", "", `{"refreshToken":"`+refresh+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens auth.Tokens
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tokens))
+	w = do(http.MethodGet, "/memory/users", tokens.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do(http.MethodPut, "/memory/users/"+alice.ID+"/role", tokens.Token, `{"role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+//nolint
+func TestUnknownBackend(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	token, err := wrapper.GenerateToken("928eeecf-05ad-4e6f-ab7f-5477225b4c52", "serhii", models.RoleUser, "jti-1", "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(NewBackends(), rates, wrapper, validator.NewValidator())
+	req := httptest.NewRequest(http.MethodGet, "/oracle/transactions/queue", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	NewRouter(srv).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"))
+	assert.JSONEq(t, `{"code":"unknown_backend","message":"unknown backend: oracle","requestId":"req-1"}`, w.Body.String())
+}
+
+//nolint
+func TestJWKS(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	srv := NewServer(NewBackends(), rates, wrapper, validator.NewValidator())
+	w := httptest.NewRecorder()
+	NewRouter(srv).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+}
+
+//nolint
+func TestErrorResponses(t *testing.T) {
+	rates := rate.NewStaticProvider("USD", nil)
+	wrapper := auth.NewJwtWrapper(auth.NewKeySet(auth.NewSecretKey("verysecretkey")), time.Hour, 24*time.Hour)
+	token, err := wrapper.GenerateToken("928eeecf-05ad-4e6f-ab7f-5477225b4c52", "serhii", models.RoleSupport, "jti-1", "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := wallet.NewService(memory.NewRepository(), nil, rates, wallet.QueueConfig{}, wallet.Timeouts{})
+	srv := NewServer(NewBackends().Register("memory", service), rates, wrapper, validator.NewValidator())
+	router := NewRouter(srv)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  bool
+		status int
+		code   string
+	}{
+		{"no token", http.MethodGet, "/memory/wallets/1", "", false, http.StatusUnauthorized, "unauthenticated"},
+		{"invalid body", http.MethodPost, "/memory/wallets", "{", true, http.StatusBadRequest, "invalid_body"},
+		{"validation", http.MethodPost, "/memory/wallets", `{"balance":1}`, true, http.StatusBadRequest, "validation_failed"},
+		{"wallet not found", http.MethodGet, "/memory/wallets/1", "", true, http.StatusNotFound, "wallet_not_found"},
+		{"invalid page", http.MethodGet, "/memory/users?limit=0", "", true, http.StatusBadRequest, "invalid_page"},
+		{"no route", http.MethodGet, "/nowhere", "", false, http.StatusNotFound, "route_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var body struct {
+				Code      string `json:"code"`
+				Message   string `json:"message"`
+				RequestID string `json:"requestId"`
+			}
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, body.Code)
+			assert.NotEmpty(t, body.Message)
+			assert.Equal(t, w.Header().Get("X-Request-ID"), body.RequestID)
+			assert.NotEmpty(t, body.RequestID)
+		})
+	}
+}
+
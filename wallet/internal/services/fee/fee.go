@@ -0,0 +1,102 @@
+package fee
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/config"
+)
+
+// Rounding decides what happens with the fractional part of a fee.
+type Rounding string
+
+const (
+	RoundUp     Rounding = "up"
+	RoundDown   Rounding = "down"
+	RoundHalfUp Rounding = "half_up"
+)
+
+// ppmScale is the precision of the rate, one millionth of the amount.
+const ppmScale = 1_000_000
+
+// Policy calculates the fee charged for a transfer and names the wallet that collects it.
+type Policy struct {
+	ratePPM  int64
+	min      int
+	max      int
+	rounding Rounding
+	walletID string
+}
+
+func NewPolicy(cfg *config.Fee) (*Policy, error) {
+	if cfg == nil {
+		return nil, errors.New("fee config is missing")
+	}
+
+	if cfg.Percent < 0 || cfg.Percent > 100 {
+		return nil, errors.Errorf("fee percent %v is out of range", cfg.Percent)
+	}
+
+	if cfg.Min < 0 || cfg.Max < 0 || (cfg.Max != 0 && cfg.Max < cfg.Min) {
+		return nil, errors.Errorf("fee caps min=%d max=%d are invalid", cfg.Min, cfg.Max)
+	}
+
+	if cfg.WalletID == "" {
+		return nil, errors.New("fee wallet is not set")
+	}
+
+	rounding := Rounding(cfg.Rounding)
+	switch rounding {
+	case "":
+		rounding = RoundUp
+	case RoundUp, RoundDown, RoundHalfUp:
+	default:
+		return nil, errors.Errorf("unknown fee rounding %q", cfg.Rounding)
+	}
+
+	return &Policy{
+		ratePPM:  int64(math.Round(cfg.Percent * ppmScale / 100)),
+		min:      cfg.Min,
+		max:      cfg.Max,
+		rounding: rounding,
+		walletID: cfg.WalletID,
+	}, nil
+}
+
+// Calculate returns the fee for amount, rounded and clamped to the configured caps.
+func (p *Policy) Calculate(amount int) int {
+	fee := p.round(int64(amount) * p.ratePPM)
+
+	if fee < p.min {
+		fee = p.min
+	}
+
+	if p.max != 0 && fee > p.max {
+		fee = p.max
+	}
+
+	return fee
+}
+
+// WalletID returns the wallet that collects the fees.
+func (p *Policy) WalletID() string {
+	return p.walletID
+}
+
+func (p *Policy) round(scaled int64) int {
+	fee, rest := scaled/ppmScale, scaled%ppmScale
+
+	switch p.rounding {
+	case RoundUp:
+		if rest > 0 {
+			fee++
+		}
+	case RoundHalfUp:
+		if rest*2 >= ppmScale {
+			fee++
+		}
+	case RoundDown:
+	}
+
+	return int(fee)
+}
@@ -0,0 +1,59 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/config"
+)
+
+//nolint
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.Fee
+		amount int
+		want   int
+	}{
+		{"percent", config.Fee{Percent: 1.5}, 1000, 15},
+		{"round up", config.Fee{Percent: 1.5}, 101, 2},
+		{"round down", config.Fee{Percent: 1.5, Rounding: "down"}, 101, 1},
+		{"round half up", config.Fee{Percent: 1.5, Rounding: "half_up"}, 130, 2},
+		{"round half up below half", config.Fee{Percent: 1.5, Rounding: "half_up"}, 90, 1},
+		{"minimum", config.Fee{Percent: 1.5, Min: 5}, 100, 5},
+		{"maximum", config.Fee{Percent: 1.5, Max: 10}, 100000, 10},
+		{"no fee", config.Fee{}, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.WalletID = "85aa7525-4fdb-4436-a600-66ffc55e0f65"
+
+			policy, err := NewPolicy(&cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policy.Calculate(tt.amount))
+		})
+	}
+}
+
+//nolint
+func TestNewPolicyError(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Fee
+	}{
+		{"missing config", nil},
+		{"missing wallet", &config.Fee{Percent: 1.5}},
+		{"negative percent", &config.Fee{Percent: -1, WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"}},
+		{"max below min", &config.Fee{Min: 10, Max: 5, WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"}},
+		{"unknown rounding", &config.Fee{Rounding: "bankers", WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicy(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package rate
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// ErrUnknownCurrency is returned when there is no quote for a currency.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Provider quotes exchange rates between currencies.
+type Provider interface {
+	Rate(from, to string) (float64, error)
+	Rates() ([]*models.Rate, error)
+}
+
+// StaticProvider serves fixed quotes against a base currency.
+type StaticProvider struct {
+	base   string
+	quotes map[string]float64
+}
+
+// NewStaticProvider creates a provider from quotes, the amount of each currency one unit of base buys.
+func NewStaticProvider(base string, quotes map[string]float64) *StaticProvider {
+	q := make(map[string]float64, len(quotes)+1)
+	for currency, value := range quotes {
+		q[currency] = value
+	}
+
+	q[base] = 1
+
+	return &StaticProvider{base: base, quotes: q}
+}
+
+type rateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NewFileProvider loads quotes from a JSON file like {"base": "USD", "rates": {"EUR": 0.98}}.
+func NewFileProvider(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading rates")
+	}
+
+	var f rateFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, errors.Wrap(err, "Error reading rates")
+	}
+
+	if f.Base == "" {
+		return nil, errors.New("rates file has no base currency")
+	}
+
+	for currency, value := range f.Rates {
+		if value <= 0 {
+			return nil, errors.Errorf("rate for %s must be positive", currency)
+		}
+	}
+
+	return NewStaticProvider(f.Base, f.Rates), nil
+}
+
+func (p *StaticProvider) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromQuote, ok := p.quotes[from]
+	if !ok {
+		return 0, errors.Wrap(ErrUnknownCurrency, from)
+	}
+
+	toQuote, ok := p.quotes[to]
+	if !ok {
+		return 0, errors.Wrap(ErrUnknownCurrency, to)
+	}
+
+	return toQuote / fromQuote, nil
+}
+
+// Rates lists the quotes of every known currency against the base currency.
+func (p *StaticProvider) Rates() ([]*models.Rate, error) {
+	rates := make([]*models.Rate, 0, len(p.quotes))
+
+	for currency, value := range p.quotes {
+		if currency == p.base {
+			continue
+		}
+
+		rates = append(rates, &models.Rate{From: p.base, To: currency, Rate: value})
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].To < rates[j].To })
+
+	return rates, nil
+}
+
+// Convert applies rate to amount and rounds to the nearest whole unit.
+func Convert(amount int, rate float64) int {
+	return int(math.Round(float64(amount) * rate))
+}
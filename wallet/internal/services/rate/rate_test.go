@@ -0,0 +1,58 @@
+package rate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+//nolint
+func TestStaticProviderRate(t *testing.T) {
+	provider := NewStaticProvider("USD", map[string]float64{"EUR": 0.5, "UAH": 40})
+
+	r, err := provider.Rate("USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, r)
+
+	r, err = provider.Rate("EUR", "UAH")
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, r)
+
+	r, err = provider.Rate("UAH", "UAH")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, r)
+}
+
+//nolint
+func TestStaticProviderRateError(t *testing.T) {
+	provider := NewStaticProvider("USD", map[string]float64{"EUR": 0.5})
+
+	_, err := provider.Rate("USD", "GBP")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+//nolint
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base": "USD", "rates": {"UAH": 36.9, "EUR": 0.98}}`), 0o600)
+	assert.NoError(t, err)
+
+	provider, err := NewFileProvider(path)
+	assert.NoError(t, err)
+
+	rates, err := provider.Rates()
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Rate{
+		{From: "USD", To: "EUR", Rate: 0.98},
+		{From: "USD", To: "UAH", Rate: 36.9},
+	}, rates)
+}
+
+//nolint
+func TestConvert(t *testing.T) {
+	assert.Equal(t, 369, Convert(10, 36.9))
+	assert.Equal(t, 98, Convert(100, 0.98))
+}
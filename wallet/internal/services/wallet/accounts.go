@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"github.com/workshops/wallet/internal/repository/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password lengths. bcrypt only uses the first 72 bytes of a password.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var (
+	unknownUserOnce sync.Once
+	unknownUserHash []byte
+)
+
+// Register creates a user that logs in with the credentials. Only a bcrypt hash of the password is stored.
+func (s *Service) Register(ctx context.Context, credentials models.Credentials) (*models.User, error) {
+	switch {
+	case credentials.Name == "":
+		return nil, Invalid("invalid_name", "name is required")
+	case len(credentials.Password) < MinPasswordLength || len(credentials.Password) > MaxPasswordLength:
+		return nil, Invalid("invalid_password", "password must be 8 to 72 bytes long")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Name: credentials.Name, PasswordHash: string(hash), Role: models.RoleUser}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	err = s.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Authenticate returns the user the credentials belong to. An unknown name takes as long to
+// reject as a wrong password, so the names of users cannot be probed.
+func (s *Service) Authenticate(ctx context.Context, credentials models.Credentials) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	user, err := s.repo.GetUserByName(ctx, credentials.Name)
+	if errors.Is(err, ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword(hashOfUnknownUser(), []byte(credentials.Password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// SetUserRole changes the role of the user with the id. Only admins may, and not their own role,
+// so there is always an admin left.
+func (s *Service) SetUserRole(ctx context.Context, id, role string) (*models.User, error) {
+	if !models.ValidRole(role) {
+		return nil, Invalid("invalid_role", "role must be user, support or admin")
+	}
+
+	if principal, ok := PrincipalFrom(ctx); ok && (!principal.HasRole(models.RoleAdmin) || principal.UserID == id) {
+		return nil, ErrForbidden
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	err := s.repo.SetUserRole(ctx, id, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUserByID(ctx, id)
+}
+
+// BootstrapAdmin makes sure the user with the credentials exists and is an admin. A missing user is
+// registered, an existing one is only promoted when the password matches, so a user who took the
+// name first cannot become admin.
+func (s *Service) BootstrapAdmin(ctx context.Context, credentials models.Credentials) error {
+	user, err := s.Authenticate(ctx, credentials)
+	if errors.Is(err, ErrInvalidCredentials) {
+		user, err = s.Register(ctx, credentials)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	return s.repo.SetUserRole(ctx, user.ID, models.RoleAdmin)
+}
+
+// hashOfUnknownUser is a hash no password matches, compared against when the user does not exist.
+func hashOfUnknownUser() []byte {
+	unknownUserOnce.Do(func() {
+		password := make([]byte, 32)
+		_, _ = rand.Read(password)
+
+		unknownUserHash, _ = bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	})
+
+	return unknownUserHash
+}
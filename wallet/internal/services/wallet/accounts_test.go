@@ -0,0 +1,78 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+//nolint
+func TestAuthenticate(t *testing.T) {
+	srvc := wallet.NewService(memory.NewRepository(), newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	ctx := context.Background()
+
+	registered, err := srvc.Register(ctx, models.Credentials{Name: "serhii", Password: "correct horse"})
+	assert.NoError(t, err)
+
+	user, err := srvc.Authenticate(ctx, models.Credentials{Name: "serhii", Password: "correct horse"})
+	assert.NoError(t, err)
+	assert.Equal(t, registered.ID, user.ID)
+
+	_, err = srvc.Authenticate(ctx, models.Credentials{Name: "serhii", Password: "battery staple"})
+	assert.ErrorIs(t, err, wallet.ErrInvalidCredentials)
+
+	_, err = srvc.Authenticate(ctx, models.Credentials{Name: "nobody", Password: "correct horse"})
+	assert.ErrorIs(t, err, wallet.ErrInvalidCredentials)
+
+	_, err = srvc.Register(ctx, models.Credentials{Name: "serhii", Password: "battery staple"})
+	assert.ErrorIs(t, err, wallet.ErrUserExists)
+
+	_, err = srvc.Register(ctx, models.Credentials{Name: "oleh", Password: "short"})
+	assert.Equal(t, "invalid_password", wallet.AsError(err).Code)
+}
+
+//nolint
+func TestBootstrapAdmin(t *testing.T) {
+	srvc := wallet.NewService(memory.NewRepository(), newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	ctx := context.Background()
+	root := models.Credentials{Name: "root", Password: "correct horse"}
+
+	assert.NoError(t, srvc.BootstrapAdmin(ctx, root))
+	assert.NoError(t, srvc.BootstrapAdmin(ctx, root), "bootstrapping again changes nothing")
+
+	user, err := srvc.Authenticate(ctx, root)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+
+	// A user who registered the name first is not promoted without the password.
+	_, err = srvc.Register(ctx, models.Credentials{Name: "boss", Password: "battery staple"})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, srvc.BootstrapAdmin(ctx, models.Credentials{Name: "boss", Password: "correct horse"}), wallet.ErrUserExists)
+}
+
+//nolint
+func TestSetUserRole(t *testing.T) {
+	srvc := wallet.NewService(memory.NewRepository(), newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	alice, err := srvc.Register(context.Background(), models.Credentials{Name: "alice", Password: "correct horse"})
+	assert.NoError(t, err)
+
+	admin := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "root", Role: models.RoleAdmin})
+	support := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "support", Role: models.RoleSupport})
+
+	_, err = srvc.SetUserRole(support, alice.ID, models.RoleAdmin)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = srvc.SetUserRole(admin, "root", models.RoleUser)
+	assert.ErrorIs(t, err, wallet.ErrForbidden, "admins cannot demote themselves")
+	_, err = srvc.SetUserRole(admin, alice.ID, "owner")
+	assert.Equal(t, "invalid_role", wallet.AsError(err).Code)
+	_, err = srvc.SetUserRole(admin, "unknown", models.RoleUser)
+	assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+
+	user, err := srvc.SetUserRole(admin, alice.ID, models.RoleSupport)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleSupport, user.Role)
+}
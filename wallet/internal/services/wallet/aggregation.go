@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// Aggregation periods.
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// Aggregation sums per day or week what a wallet sent, in its own currency, and what it received,
+// in the currency of the receiver. It is used by backends that cannot group in the database.
+type Aggregation struct {
+	walletID string
+	period   string
+	days     map[time.Time]*models.Day
+}
+
+func NewAggregation(walletID, period string) *Aggregation {
+	return &Aggregation{walletID: walletID, period: period, days: make(map[time.Time]*models.Day)}
+}
+
+// Add counts the transaction made at date. Transactions of other wallets are ignored.
+func (a *Aggregation) Add(transaction *models.Transaction, date time.Time) {
+	if transaction.CreditWalletID != a.walletID && transaction.DebitWalletID != a.walletID {
+		return
+	}
+
+	start := PeriodStart(date, a.period)
+
+	day, ok := a.days[start]
+	if !ok {
+		day = &models.Day{Date: start.Format(time.RFC3339Nano)}
+		a.days[start] = day
+	}
+
+	if transaction.CreditWalletID == a.walletID {
+		day.Outcome += transaction.Amount
+	}
+
+	if transaction.DebitWalletID == a.walletID {
+		day.Income += transaction.DebitAmount
+	}
+}
+
+// Days returns the totals, oldest period first.
+func (a *Aggregation) Days() []*models.Day {
+	starts := make([]time.Time, 0, len(a.days))
+	for start := range a.days {
+		starts = append(starts, start)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	days := make([]*models.Day, 0, len(starts))
+	for _, start := range starts {
+		days = append(days, a.days[start])
+	}
+
+	return days
+}
+
+// PeriodStart truncates t, in UTC, to the start of its day or week. Weeks start on Monday,
+// like date_trunc in Postgres.
+func PeriodStart(t time.Time, period string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	if period == PeriodWeek {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+
+	return day
+}
+
+// ParseWeek reads the bounds of an aggregation, each a date or a full RFC 3339 timestamp.
+func ParseWeek(week models.Week) (time.Time, time.Time, error) {
+	from, err := parseDate(week.DateFrom)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, err := parseDate(week.DateTo)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339Nano} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, Invalid("invalid_date", fmt.Sprintf("invalid date %q", value))
+}
@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/workshops/wallet/internal/services/rate"
+)
+
+var (
+	// ErrWalletNotFound is returned when a wallet does not exist.
+	ErrWalletNotFound = errors.New("wallet not found")
+	// ErrInsufficientFunds is returned when the sender cannot cover the amount and the fee.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrSameWallet is returned when a transfer names the same wallet on both sides.
+	ErrSameWallet = errors.New("cannot transfer to the same wallet")
+	// ErrInvalidAmount is returned when a transfer does not move a positive amount.
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrTransactionNotFound is returned by repositories when no transaction matches the lookup.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrDuplicateIdempotencyKey is returned by repositories when another transaction
+	// already holds the idempotency key.
+	ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is replayed with a different payload.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different transaction")
+	// ErrInvalidCursor is returned when a page cursor was not issued by a previous page.
+	ErrInvalidCursor = errors.New("invalid page cursor")
+	// ErrInvalidFilter is returned when a transaction filter contradicts itself.
+	ErrInvalidFilter = errors.New("invalid transaction filter")
+	// ErrAlreadyReversed is returned when the whole amount of a transaction was already refunded.
+	ErrAlreadyReversed = errors.New("transaction is already reversed")
+	// ErrReversalExceedsAmount is returned when a refund is larger than what is left of the transaction.
+	ErrReversalExceedsAmount = errors.New("reversal exceeds the transaction amount")
+	// ErrReversalOfReversal is returned when a reversal itself is asked to be reversed.
+	ErrReversalOfReversal = errors.New("a reversal cannot be reversed")
+	// ErrUserExists is returned by repositories when another user already has the name.
+	ErrUserExists = errors.New("user name is already taken")
+	// ErrUserNotFound is returned by repositories when no user can log in with the name.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidCredentials is returned when the name or the password of a login is wrong.
+	ErrInvalidCredentials = errors.New("invalid name or password")
+	// ErrRefreshTokenNotFound is returned by repositories when no refresh token has the hash.
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenReused is returned when a refresh token is presented after it was used.
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrForbidden is returned when the caller acts on a wallet or transaction of another user.
+	ErrForbidden = errors.New("access to the resource is not allowed")
+)
+
+// Kind is the class of an Error. Each API maps it to its own status code.
+type Kind int
+
+const (
+	// KindInternal is a failure of the service itself, its cause is not shown to clients.
+	KindInternal Kind = iota
+	// KindInvalid is a request that cannot be served as sent.
+	KindInvalid
+	// KindUnauthenticated is a request without valid credentials.
+	KindUnauthenticated
+	// KindForbidden is a request of a caller who may not act on what it names.
+	KindForbidden
+	// KindNotFound is a request for a wallet or transaction that does not exist.
+	KindNotFound
+	// KindConflict is a request that clashes with an earlier one.
+	KindConflict
+	// KindRejected is a well-formed request the state of the wallets does not allow.
+	KindRejected
+	// KindUnavailable is a request the service cannot take right now, it may be retried later.
+	KindUnavailable
+	// KindTimeout is a request that did not finish in time.
+	KindTimeout
+)
+
+// Error is an error the APIs describe to clients with a machine-readable code, such as
+// wallet_not_found, and a message. The cause is kept for logs and errors.Is.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Invalid reports a request that cannot be served as sent.
+func Invalid(code, message string) *Error {
+	return &Error{Kind: KindInvalid, Code: code, Message: message}
+}
+
+// known describes the errors of the service and of what it depends on.
+var known = []struct {
+	err  error
+	kind Kind
+	code string
+}{
+	{ErrInvalidCredentials, KindUnauthenticated, "invalid_credentials"},
+	{ErrRefreshTokenReused, KindUnauthenticated, "refresh_token_reused"},
+	{ErrInvalidRefreshToken, KindUnauthenticated, "invalid_refresh_token"},
+	{ErrForbidden, KindForbidden, "forbidden"},
+	{ErrWalletNotFound, KindNotFound, "wallet_not_found"},
+	{ErrTransactionNotFound, KindNotFound, "transaction_not_found"},
+	{ErrUserNotFound, KindNotFound, "user_not_found"},
+	{ErrSameWallet, KindInvalid, "same_wallet"},
+	{ErrInvalidAmount, KindInvalid, "invalid_amount"},
+	{ErrInvalidCursor, KindInvalid, "invalid_cursor"},
+	{ErrInvalidFilter, KindInvalid, "invalid_filter"},
+	{ErrReversalOfReversal, KindInvalid, "reversal_of_reversal"},
+	{ErrUserExists, KindConflict, "user_exists"},
+	{ErrIdempotencyKeyReused, KindConflict, "idempotency_key_reused"},
+	{ErrDuplicateIdempotencyKey, KindConflict, "duplicate_idempotency_key"},
+	{ErrAlreadyReversed, KindConflict, "already_reversed"},
+	{ErrInsufficientFunds, KindRejected, "insufficient_funds"},
+	{ErrReversalExceedsAmount, KindRejected, "reversal_exceeds_amount"},
+	{rate.ErrUnknownCurrency, KindRejected, "unknown_currency"},
+	{ErrQueueFull, KindUnavailable, "queue_full"},
+	{ErrQueueClosed, KindUnavailable, "queue_closed"},
+	{context.DeadlineExceeded, KindTimeout, "timeout"},
+}
+
+// AsError describes err to clients. Errors the service does not know are internal.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	for _, k := range known {
+		if errors.Is(err, k.err) {
+			return &Error{Kind: k.kind, Code: k.code, Message: k.err.Error(), Err: err}
+		}
+	}
+
+	return &Error{Kind: KindInternal, Code: "internal", Message: "internal error", Err: err}
+}
@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/services/rate"
+)
+
+//nolint
+func TestAsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    Kind
+		code    string
+		message string
+	}{
+		{"sentinel", ErrWalletNotFound, KindNotFound, "wallet_not_found", "wallet not found"},
+		{"wrapped", fmt.Errorf("Error from db: %w", ErrInsufficientFunds), KindRejected, "insufficient_funds", "insufficient funds"},
+		{"other package", fmt.Errorf("EUR: %w", rate.ErrUnknownCurrency), KindRejected, "unknown_currency", "unknown currency"},
+		{"deadline", context.DeadlineExceeded, KindTimeout, "timeout", "context deadline exceeded"},
+		{"described", Invalid("invalid_date", `invalid date "x"`), KindInvalid, "invalid_date", `invalid date "x"`},
+		{"unknown", errors.New("pq: connection refused"), KindInternal, "internal", "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := AsError(tt.err)
+			assert.Equal(t, tt.kind, e.Kind)
+			assert.Equal(t, tt.code, e.Code)
+			assert.Equal(t, tt.message, e.Message)
+			assert.ErrorIs(t, e, tt.err)
+		})
+	}
+}
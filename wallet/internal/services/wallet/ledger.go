@@ -0,0 +1,102 @@
+package wallet
+
+import (
+	"strings"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// System accounts are named prefix plus currency, wallets are their own account named by wallet id.
+const (
+	// fxAccount balances the legs of a transfer between currencies.
+	fxAccount = "fx:"
+	// equityAccount is the counterpart of the opening balance of a wallet.
+	equityAccount = "equity:"
+)
+
+// IsWalletAccount reports whether the ledger account belongs to a wallet rather than the system.
+func IsWalletAccount(account string) bool {
+	return !strings.Contains(account, ":")
+}
+
+// TransferEntries returns the postings of a transfer: the sender pays the amount and the fee,
+// the receiver and the fee wallet are paid in their own currencies, and the fx accounts absorb
+// the conversion so that every currency nets to zero. A reversal posts the mirror image.
+func TransferEntries(transaction *models.Transaction) []*models.LedgerEntry {
+	if transaction.OriginalTransactionID != "" {
+		return reversalEntries(transaction)
+	}
+
+	from := transaction.Currency
+	to := transaction.DebitCurrency
+
+	entries := []*models.LedgerEntry{
+		{Account: transaction.CreditWalletID, Amount: -(transaction.Amount + transaction.FeeAmount), Currency: from},
+		{Account: transaction.DebitWalletID, Amount: transaction.DebitAmount, Currency: to},
+	}
+
+	if transaction.FeeAmount != 0 {
+		entries = append(entries, &models.LedgerEntry{
+			Account: transaction.FeeWalletID, Amount: transaction.FeeWalletAmount, Currency: transaction.FeeCurrency,
+		})
+	}
+
+	if to != from {
+		entries = append(entries,
+			&models.LedgerEntry{Account: fxAccount + from, Amount: transaction.Amount, Currency: from},
+			&models.LedgerEntry{Account: fxAccount + to, Amount: -transaction.DebitAmount, Currency: to},
+		)
+	}
+
+	if transaction.FeeAmount != 0 && transaction.FeeCurrency != from {
+		entries = append(entries,
+			&models.LedgerEntry{Account: fxAccount + from, Amount: transaction.FeeAmount, Currency: from},
+			&models.LedgerEntry{Account: fxAccount + transaction.FeeCurrency, Amount: -transaction.FeeWalletAmount, Currency: transaction.FeeCurrency},
+		)
+	}
+
+	for _, entry := range entries {
+		entry.TransactionID = transaction.ID
+		entry.Date = transaction.Date
+	}
+
+	return entries
+}
+
+// OpeningEntries returns the postings that fund a new wallet with its initial balance.
+func OpeningEntries(w *models.Wallet) []*models.LedgerEntry {
+	if w.Balance == 0 {
+		return nil
+	}
+
+	return []*models.LedgerEntry{
+		{Account: w.ID, Amount: w.Balance, Currency: w.Currency},
+		{Account: equityAccount + w.Currency, Amount: -w.Balance, Currency: w.Currency},
+	}
+}
+
+// reversalEntries negates the postings of the transfer the reversal refunds: the receiver of the
+// original pays Amount back, the original sender gets DebitAmount plus the refunded fee.
+func reversalEntries(reversal *models.Transaction) []*models.LedgerEntry {
+	refunded := &models.Transaction{
+		ID:              reversal.ID,
+		CreditWalletID:  reversal.DebitWalletID,
+		DebitWalletID:   reversal.CreditWalletID,
+		Amount:          reversal.DebitAmount,
+		Currency:        reversal.DebitCurrency,
+		DebitAmount:     reversal.Amount,
+		DebitCurrency:   reversal.Currency,
+		FeeAmount:       reversal.FeeAmount,
+		FeeWalletID:     reversal.FeeWalletID,
+		FeeWalletAmount: reversal.FeeWalletAmount,
+		FeeCurrency:     reversal.FeeCurrency,
+		Date:            reversal.Date,
+	}
+
+	entries := TransferEntries(refunded)
+	for _, entry := range entries {
+		entry.Amount = -entry.Amount
+	}
+
+	return entries
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+func sums(entries []*models.LedgerEntry) map[string]int {
+	totals := make(map[string]int)
+
+	for _, entry := range entries {
+		totals[entry.Currency] += entry.Amount
+	}
+
+	return totals
+}
+
+//nolint
+func TestTransferEntriesBalanced(t *testing.T) {
+	transaction := &models.Transaction{
+		ID:              "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
+		CreditWalletID:  "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:   "096a20c7-0b2a-475a-b175-229196f23cde",
+		FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+		Amount:          200,
+		Currency:        "USD",
+		DebitAmount:     100,
+		DebitCurrency:   "EUR",
+		FeeAmount:       3,
+		FeeWalletAmount: 111,
+		FeeCurrency:     "UAH",
+	}
+
+	entries := TransferEntries(transaction)
+
+	assert.Len(t, entries, 7)
+	assert.Equal(t, map[string]int{"USD": 0, "EUR": 0, "UAH": 0}, sums(entries))
+
+	for _, entry := range entries {
+		assert.Equal(t, transaction.ID, entry.TransactionID)
+	}
+}
+
+//nolint
+func TestTransferEntriesSameCurrency(t *testing.T) {
+	transaction := &models.Transaction{
+		CreditWalletID:  "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:   "096a20c7-0b2a-475a-b175-229196f23cde",
+		FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+		Amount:          200,
+		Currency:        "USD",
+		DebitAmount:     200,
+		DebitCurrency:   "USD",
+		FeeAmount:       3,
+		FeeWalletAmount: 3,
+		FeeCurrency:     "USD",
+	}
+
+	entries := TransferEntries(transaction)
+
+	assert.Len(t, entries, 3)
+	assert.Equal(t, map[string]int{"USD": 0}, sums(entries))
+	assert.Equal(t, -203, entries[0].Amount)
+}
+
+//nolint
+func TestOpeningEntries(t *testing.T) {
+	entries := OpeningEntries(&models.Wallet{ID: "ce71eb21-1312-4e29-89df-039cae56007a", Balance: 100, Currency: "EUR"})
+
+	assert.Equal(t, map[string]int{"EUR": 0}, sums(entries))
+	assert.Equal(t, "equity:EUR", entries[1].Account)
+	assert.Empty(t, OpeningEntries(&models.Wallet{Currency: "EUR"}))
+}
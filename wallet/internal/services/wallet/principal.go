@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// Principal is the authenticated user a request is made by.
+type Principal struct {
+	UserID string
+	Name   string
+	Role   string
+}
+
+// HasRole reports whether the principal has one of the roles.
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStaff reports whether the principal is support staff or an admin, who may see every wallet.
+func (p *Principal) isStaff() bool {
+	return p.HasRole(models.RoleSupport, models.RoleAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries the caller. The APIs set it from the token.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller of ctx, or false when the call comes from the server itself.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// authorizeUser checks that the caller acts for the user. Calls without a principal are not
+// made on behalf of a client and are not restricted.
+func authorizeUser(ctx context.Context, userID string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if ok && principal.UserID != userID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// authorizeUserOrStaff checks that the caller acts for the user or is staff, who may read the data of anyone.
+func authorizeUserOrStaff(ctx context.Context, userID string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if ok && principal.isStaff() {
+		return nil
+	}
+
+	return authorizeUser(ctx, userID)
+}
+
+// authorizeStaff checks that the caller is staff.
+func authorizeStaff(ctx context.Context) error {
+	principal, ok := PrincipalFrom(ctx)
+	if ok && !principal.isStaff() {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// authorizeWallet checks that the caller owns the wallet with the id. The wallet is only
+// looked up for calls on behalf of a client.
+func (s *Service) authorizeWallet(ctx context.Context, id string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	return s.authorizeWalletOf(ctx, id, principal)
+}
+
+// authorizeWalletOrStaff is authorizeWallet that also lets staff through, for the reads they may
+// make on any wallet.
+func (s *Service) authorizeWalletOrStaff(ctx context.Context, id string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || principal.isStaff() {
+		return nil
+	}
+
+	return s.authorizeWalletOf(ctx, id, principal)
+}
+
+func (s *Service) authorizeWalletOf(ctx context.Context, id string, principal *Principal) error {
+	wallet, err := s.repo.GetWalletByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if wallet.UserID != principal.UserID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// ownTransactions limits a list of all transactions to those of the caller's wallets. Staff see them all.
+func ownTransactions(ctx context.Context, filter models.TransactionFilter) models.TransactionFilter {
+	if principal, ok := PrincipalFrom(ctx); ok && !principal.isStaff() {
+		filter.UserID = principal.UserID
+	}
+
+	return filter
+}
@@ -0,0 +1,108 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+//nolint
+func TestOwnership(t *testing.T) {
+	repo := memory.NewRepository(&models.Wallet{ID: "85aa7525-4fdb-4436-a600-66ffc55e0f65", UserID: "system", Currency: "USD"})
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	defer srvc.Close()
+
+	alice := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "alice", Role: models.RoleUser})
+	bob := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "bob", Role: models.RoleUser})
+	support := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "support", Role: models.RoleSupport})
+
+	alices := &models.Wallet{UserID: "alice", Balance: 1000}
+	require.NoError(t, srvc.CreateWallet(alice, alices))
+	bobs := &models.Wallet{UserID: "bob", Balance: 1000}
+	require.NoError(t, srvc.CreateWallet(bob, bobs))
+
+	assert.ErrorIs(t, srvc.CreateWallet(alice, &models.Wallet{UserID: "bob"}), wallet.ErrForbidden)
+
+	_, err := srvc.GetWalletByID(alice, alices.ID)
+	assert.NoError(t, err)
+	_, err = srvc.GetWalletByID(alice, bobs.ID)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, _, err = srvc.GetWalletTransactionsByID(alice, bobs.ID, models.TransactionFilter{}, models.Pagination{})
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = srvc.GetWalletLedger(alice, bobs.ID)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+
+	stolen := &models.Transaction{CreditWalletID: bobs.ID, DebitWalletID: alices.ID, Amount: 100}
+	assert.ErrorIs(t, srvc.CreateTransaction(alice, stolen), wallet.ErrForbidden)
+
+	paid := &models.Transaction{CreditWalletID: alices.ID, DebitWalletID: bobs.ID, Amount: 100}
+	require.NoError(t, srvc.CreateTransaction(alice, paid))
+
+	// Only staff reverse transfers, not even the receiver may.
+	_, err = srvc.ReverseTransaction(alice, paid.ID, &models.Reversal{})
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = srvc.ReverseTransaction(bob, paid.ID, &models.Reversal{Amount: 10})
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = srvc.ReverseTransaction(support, paid.ID, &models.Reversal{Amount: 10})
+	assert.NoError(t, err)
+
+	// Staff read every wallet and transaction, but do not spend from them.
+	_, err = srvc.GetWalletByID(support, bobs.ID)
+	assert.NoError(t, err)
+	_, err = srvc.GetWalletLedger(support, bobs.ID)
+	assert.NoError(t, err)
+	_, _, err = srvc.GetWalletTransactionsByID(support, bobs.ID, models.TransactionFilter{}, models.Pagination{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, srvc.CreateTransaction(support, &models.Transaction{CreditWalletID: bobs.ID, DebitWalletID: alices.ID, Amount: 1}), wallet.ErrForbidden)
+	list, _, err := srvc.GetTransactions(support, models.TransactionFilter{}, models.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// A user without wallets sees no transaction.
+	carol := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "carol"})
+	list, _, err = srvc.GetTransactions(carol, models.TransactionFilter{}, models.Pagination{})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	list, _, err = srvc.GetTransactions(bob, models.TransactionFilter{}, models.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// The server itself is not restricted.
+	_, err = srvc.GetWalletByID(context.Background(), bobs.ID)
+	assert.NoError(t, err)
+}
+
+//nolint
+func TestNonPositiveAmount(t *testing.T) {
+	repo := memory.NewRepository(&models.Wallet{ID: "85aa7525-4fdb-4436-a600-66ffc55e0f65", UserID: "system", Currency: "USD"})
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	defer srvc.Close()
+
+	mallory := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "mallory", Role: models.RoleUser})
+	alice := wallet.WithPrincipal(context.Background(), &wallet.Principal{UserID: "alice", Role: models.RoleUser})
+
+	mallorys := &models.Wallet{UserID: "mallory", Balance: 10}
+	require.NoError(t, srvc.CreateWallet(mallory, mallorys))
+	alices := &models.Wallet{UserID: "alice", Balance: 1000}
+	require.NoError(t, srvc.CreateWallet(alice, alices))
+
+	// A negative transfer to alice would pull her money into the wallet of mallory.
+	for _, amount := range []int{-500, 0} {
+		err := srvc.CreateTransaction(mallory, &models.Transaction{CreditWalletID: mallorys.ID, DebitWalletID: alices.ID, Amount: amount})
+		assert.ErrorIs(t, err, wallet.ErrInvalidAmount)
+		assert.Equal(t, wallet.KindInvalid, wallet.AsError(err).Kind)
+	}
+
+	found, err := repo.GetWalletByID(context.Background(), mallorys.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, found.Balance)
+	found, err = repo.GetWalletByID(context.Background(), alices.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, found.Balance)
+}
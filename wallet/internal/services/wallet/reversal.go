@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/rate"
+)
+
+// newReversal builds the transaction that refunds reversal.Amount of original, measured in the
+// currency of original. The receiver pays back its share and the sender is refunded, together with
+// the matching share of the fee when it is refunded too.
+func newReversal(original *models.Transaction, reversal *models.Reversal) (*models.Transaction, error) {
+	if original.OriginalTransactionID != "" {
+		return nil, ErrReversalOfReversal
+	}
+
+	remaining := original.Amount - original.RefundedAmount
+	if remaining <= 0 {
+		return nil, ErrAlreadyReversed
+	}
+
+	amount := reversal.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+
+	if amount > remaining {
+		return nil, ErrReversalExceedsAmount
+	}
+
+	share := float64(amount) / float64(original.Amount)
+
+	transaction := &models.Transaction{
+		CreditWalletID:        original.DebitWalletID,
+		DebitWalletID:         original.CreditWalletID,
+		Amount:                rate.Convert(original.DebitAmount, share),
+		Currency:              original.DebitCurrency,
+		DebitAmount:           amount,
+		DebitCurrency:         original.Currency,
+		Rate:                  1,
+		Type:                  original.Type,
+		FeeWalletID:           original.FeeWalletID,
+		FeeCurrency:           original.FeeCurrency,
+		IdempotencyKey:        reversal.IdempotencyKey,
+		OriginalTransactionID: original.ID,
+	}
+
+	if original.Rate != 0 {
+		transaction.Rate = 1 / original.Rate
+	}
+
+	if reversal.RefundFee {
+		transaction.FeeAmount = rate.Convert(original.FeeAmount, share)
+		transaction.FeeWalletAmount = rate.Convert(original.FeeWalletAmount, share)
+
+		// Rounded shares of several partial refunds must not add up to more than the fee. Once the
+		// rest of the fee is refunded, the fee wallet gives back the rest of what it received.
+		left := original.FeeAmount - original.RefundedFeeAmount
+		leftInFeeWallet := original.FeeWalletAmount - original.RefundedFeeWalletAmount
+
+		if transaction.FeeAmount >= left {
+			transaction.FeeAmount = left
+			transaction.FeeWalletAmount = leftInFeeWallet
+		}
+
+		if transaction.FeeWalletAmount > leftInFeeWallet {
+			transaction.FeeWalletAmount = leftInFeeWallet
+		}
+
+		// Without a fee there is no fee posting, a later refund gives the fee wallet amount back.
+		if transaction.FeeAmount == 0 {
+			transaction.FeeWalletAmount = 0
+		}
+	}
+
+	return transaction, nil
+}
+
+// CheckRefunds validates the original transaction once the reversal was added to its refunded totals.
+// Repositories call it while the original is locked, so concurrent reversals cannot overdraw it.
+func CheckRefunds(original, reversal *models.Transaction) error {
+	if original.RefundedAmount-reversal.DebitAmount >= original.Amount {
+		return ErrAlreadyReversed
+	}
+
+	if original.RefundedAmount > original.Amount || original.RefundedFeeAmount > original.FeeAmount ||
+		original.RefundedFeeWalletAmount > original.FeeWalletAmount {
+		return ErrReversalExceedsAmount
+	}
+
+	return nil
+}
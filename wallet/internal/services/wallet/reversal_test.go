@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+func newOriginal() *models.Transaction {
+	return &models.Transaction{
+		ID:              "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
+		CreditWalletID:  "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:   "096a20c7-0b2a-475a-b175-229196f23cde",
+		FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+		Amount:          200,
+		Currency:        "USD",
+		DebitAmount:     100,
+		DebitCurrency:   "EUR",
+		Rate:            0.5,
+		FeeAmount:       3,
+		FeeWalletAmount: 3,
+		FeeCurrency:     "USD",
+	}
+}
+
+//nolint
+func TestNewReversalFull(t *testing.T) {
+	original := newOriginal()
+
+	reversal, err := newReversal(original, &models.Reversal{RefundFee: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, original.DebitWalletID, reversal.CreditWalletID)
+	assert.Equal(t, original.CreditWalletID, reversal.DebitWalletID)
+	assert.Equal(t, 100, reversal.Amount)
+	assert.Equal(t, "EUR", reversal.Currency)
+	assert.Equal(t, 200, reversal.DebitAmount)
+	assert.Equal(t, 3, reversal.FeeAmount)
+	assert.Equal(t, original.ID, reversal.OriginalTransactionID)
+
+	// The reversal undoes every posting of the original.
+	totals := make(map[string]int)
+	for _, entry := range append(TransferEntries(original), TransferEntries(reversal)...) {
+		totals[entry.Account] += entry.Amount
+	}
+
+	for account, total := range totals {
+		assert.Zero(t, total, account)
+	}
+}
+
+//nolint
+func TestNewReversalPartial(t *testing.T) {
+	original := newOriginal()
+	original.RefundedAmount = 150
+
+	reversal, err := newReversal(original, &models.Reversal{})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, reversal.DebitAmount)
+	assert.Zero(t, reversal.FeeAmount)
+
+	_, err = newReversal(original, &models.Reversal{Amount: 51})
+	assert.ErrorIs(t, err, ErrReversalExceedsAmount)
+
+	original.RefundedAmount = 200
+
+	_, err = newReversal(original, &models.Reversal{})
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+}
+
+//nolint
+func TestNewReversalPartialFees(t *testing.T) {
+	original := &models.Transaction{
+		ID:              "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
+		CreditWalletID:  "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:   "096a20c7-0b2a-475a-b175-229196f23cde",
+		FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+		Amount:          3,
+		DebitAmount:     3,
+		Rate:            1,
+		FeeAmount:       2,
+		FeeWalletAmount: 2,
+	}
+
+	// Each third of the fee rounds up to 1, so the last refund finds less fee left than its share.
+	feeAmounts := make([]int, 0, 3)
+	feeWalletAmounts := make([]int, 0, 3)
+
+	for i := 0; i < 3; i++ {
+		reversal, err := newReversal(original, &models.Reversal{Amount: 1, RefundFee: true})
+		assert.NoError(t, err)
+
+		original.RefundedAmount += reversal.DebitAmount
+		original.RefundedFeeAmount += reversal.FeeAmount
+		original.RefundedFeeWalletAmount += reversal.FeeWalletAmount
+		assert.NoError(t, CheckRefunds(original, reversal))
+
+		feeAmounts = append(feeAmounts, reversal.FeeAmount)
+		feeWalletAmounts = append(feeWalletAmounts, reversal.FeeWalletAmount)
+	}
+
+	assert.Equal(t, []int{1, 1, 0}, feeAmounts)
+	assert.Equal(t, []int{1, 1, 0}, feeWalletAmounts, "the fee wallet gives back what it received, no more")
+	assert.Equal(t, original.FeeWalletAmount, original.RefundedFeeWalletAmount)
+}
+
+//nolint
+func TestNewReversalFeeInOtherCurrency(t *testing.T) {
+	original := newOriginal()
+	original.FeeWalletAmount = 7
+
+	// The refund that takes the rest of the fee takes the rest of the fee wallet amount too.
+	original.RefundedAmount = 100
+	original.RefundedFeeAmount = 2
+	original.RefundedFeeWalletAmount = 4
+
+	reversal, err := newReversal(original, &models.Reversal{RefundFee: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reversal.FeeAmount)
+	assert.Equal(t, 3, reversal.FeeWalletAmount)
+}
+
+//nolint
+func TestNewReversalOfReversal(t *testing.T) {
+	original := newOriginal()
+	original.OriginalTransactionID = "b25abc6c-63c5-46a4-bf0c-f355a23edc2e"
+
+	_, err := newReversal(original, &models.Reversal{})
+	assert.ErrorIs(t, err, ErrReversalOfReversal)
+}
+
+//nolint
+func TestCheckRefunds(t *testing.T) {
+	reversal := &models.Transaction{DebitAmount: 200}
+
+	original := newOriginal()
+	original.RefundedAmount = 200
+	assert.NoError(t, CheckRefunds(original, reversal))
+
+	// A concurrent full reversal committed first.
+	original.RefundedAmount = 400
+	assert.ErrorIs(t, CheckRefunds(original, reversal), ErrAlreadyReversed)
+
+	original.RefundedAmount = 250
+	assert.ErrorIs(t, CheckRefunds(original, &models.Transaction{DebitAmount: 100}), ErrReversalExceedsAmount)
+
+	original.RefundedAmount = 200
+	original.RefundedFeeWalletAmount = 4
+	assert.ErrorIs(t, CheckRefunds(original, reversal), ErrReversalExceedsAmount)
+}
@@ -0,0 +1,181 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gammazero/deque"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// Transaction priorities stored in models.Transaction.Type.
+const (
+	PriorityLow  = 0
+	PriorityHigh = 1
+)
+
+var (
+	// ErrQueueFull is returned when the scheduler has no room for another transaction.
+	ErrQueueFull = errors.New("transaction queue is full")
+	// ErrQueueClosed is returned when a transaction is submitted after the service was closed.
+	ErrQueueClosed = errors.New("transaction queue is closed")
+)
+
+// QueueConfig configures the priority scheduler that runs transactions.
+type QueueConfig struct {
+	// Workers is the number of transactions processed concurrently.
+	Workers int
+	// Capacity bounds the number of waiting transactions of both priorities.
+	Capacity int
+	// MaxWait is how long a low priority transaction may wait before it is served ahead of high priority ones.
+	MaxWait time.Duration
+}
+
+// QueueDepth is the number of waiting transactions per priority.
+type QueueDepth struct {
+	High int `json:"high"`
+	Low  int `json:"low"`
+}
+
+type job struct {
+	ctx         context.Context
+	transaction *models.Transaction
+	enqueued    time.Time
+	result      chan error
+}
+
+// scheduler is a bounded two level priority queue drained by a pool of workers.
+// Low priority jobs age: once the oldest one waited MaxWait it is served first.
+type scheduler struct {
+	cfg     QueueConfig
+	process func(context.Context, *models.Transaction) error
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	high   *deque.Deque
+	low    *deque.Deque
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newScheduler(cfg QueueConfig, process func(context.Context, *models.Transaction) error) *scheduler {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 100
+	}
+
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = time.Second
+	}
+
+	s := &scheduler{
+		cfg:     cfg,
+		process: process,
+		high:    deque.New(),
+		low:     deque.New(),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	return s
+}
+
+func (s *scheduler) start() {
+	for i := 0; i < s.cfg.Workers; i++ {
+		s.wg.Add(1)
+
+		go s.work()
+	}
+}
+
+// submit enqueues the transaction and returns the channel its result is delivered on.
+// The transaction is processed with ctx, and skipped when ctx is done before a worker takes it.
+func (s *scheduler) submit(ctx context.Context, transaction *models.Transaction) (<-chan error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrQueueClosed
+	}
+
+	if s.high.Len()+s.low.Len() >= s.cfg.Capacity {
+		return nil, ErrQueueFull
+	}
+
+	j := &job{ctx: ctx, transaction: transaction, enqueued: time.Now(), result: make(chan error, 1)}
+	if transaction.Type == PriorityHigh {
+		s.high.PushBack(j)
+	} else {
+		s.low.PushBack(j)
+	}
+
+	s.cond.Signal()
+
+	return j.result, nil
+}
+
+// next blocks until a job is available. It returns false once the scheduler is closed and drained.
+func (s *scheduler) next() (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.high.Len() == 0 && s.low.Len() == 0 {
+		if s.closed {
+			return nil, false
+		}
+
+		s.cond.Wait()
+	}
+
+	return s.pop(time.Now()), true
+}
+
+func (s *scheduler) pop(now time.Time) *job {
+	if s.low.Len() != 0 {
+		oldest := s.low.Front().(*job)
+		if s.high.Len() == 0 || now.Sub(oldest.enqueued) >= s.cfg.MaxWait {
+			return s.low.PopFront().(*job)
+		}
+	}
+
+	return s.high.PopFront().(*job)
+}
+
+func (s *scheduler) work() {
+	defer s.wg.Done()
+
+	for {
+		j, ok := s.next()
+		if !ok {
+			return
+		}
+
+		if err := j.ctx.Err(); err != nil {
+			j.result <- err
+			continue
+		}
+
+		j.result <- s.process(j.ctx, j.transaction)
+	}
+}
+
+func (s *scheduler) depth() QueueDepth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return QueueDepth{High: s.high.Len(), Low: s.low.Len()}
+}
+
+// close stops accepting transactions and waits until the queued ones are processed.
+func (s *scheduler) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
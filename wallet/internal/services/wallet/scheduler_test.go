@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+func noop(context.Context, *models.Transaction) error {
+	return nil
+}
+
+//nolint
+func TestSchedulerHighPriorityFirst(t *testing.T) {
+	s := newScheduler(QueueConfig{MaxWait: time.Hour}, noop)
+
+	low := &models.Transaction{ID: "low", Type: PriorityLow}
+	high := &models.Transaction{ID: "high", Type: PriorityHigh}
+
+	_, err := s.submit(context.Background(), low)
+	assert.NoError(t, err)
+	_, err = s.submit(context.Background(), high)
+	assert.NoError(t, err)
+
+	assert.Equal(t, QueueDepth{High: 1, Low: 1}, s.depth())
+	assert.Equal(t, high, s.pop(time.Now()).transaction)
+	assert.Equal(t, low, s.pop(time.Now()).transaction)
+}
+
+//nolint
+func TestSchedulerAgesLowPriority(t *testing.T) {
+	s := newScheduler(QueueConfig{MaxWait: time.Minute}, noop)
+
+	low := &models.Transaction{ID: "low", Type: PriorityLow}
+	high := &models.Transaction{ID: "high", Type: PriorityHigh}
+
+	_, err := s.submit(context.Background(), low)
+	assert.NoError(t, err)
+	_, err = s.submit(context.Background(), high)
+	assert.NoError(t, err)
+
+	assert.Equal(t, low, s.pop(time.Now().Add(time.Minute)).transaction)
+}
+
+//nolint
+func TestSchedulerFull(t *testing.T) {
+	s := newScheduler(QueueConfig{Capacity: 1}, noop)
+
+	_, err := s.submit(context.Background(), &models.Transaction{})
+	assert.NoError(t, err)
+
+	_, err = s.submit(context.Background(), &models.Transaction{})
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+//nolint
+func TestSchedulerCloseDrains(t *testing.T) {
+	processed := 0
+	s := newScheduler(QueueConfig{Workers: 1}, func(context.Context, *models.Transaction) error {
+		processed++
+		return nil
+	})
+
+	results := make([]<-chan error, 0)
+
+	for i := 0; i < 3; i++ {
+		result, err := s.submit(context.Background(), &models.Transaction{})
+		assert.NoError(t, err)
+
+		results = append(results, result)
+	}
+
+	s.start()
+	s.close()
+
+	for _, result := range results {
+		assert.NoError(t, <-result)
+	}
+
+	assert.Equal(t, 3, processed)
+
+	_, err := s.submit(context.Background(), &models.Transaction{})
+	assert.ErrorIs(t, err, ErrQueueClosed)
+}
+
+//nolint
+func TestSchedulerSkipsCanceled(t *testing.T) {
+	processed := 0
+	s := newScheduler(QueueConfig{Workers: 1}, func(context.Context, *models.Transaction) error {
+		processed++
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, err := s.submit(ctx, &models.Transaction{})
+	assert.NoError(t, err)
+
+	cancel()
+	s.start()
+	s.close()
+
+	assert.ErrorIs(t, <-result, context.Canceled)
+	assert.Equal(t, 0, processed)
+}
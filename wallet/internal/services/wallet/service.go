@@ -1,73 +1,494 @@
 package wallet
 
 import (
-	"github.com/gammazero/deque"
+	"context"
+	"errors"
+	"time"
+
 	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/fee"
+	"github.com/workshops/wallet/internal/services/rate"
+)
+
+// DefaultCurrency is used for wallets created without a currency.
+const DefaultCurrency = "USD"
+
+// Page sizes of list requests.
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 50
 )
 
 type Repository interface {
-	CreateUser(token string) error
-	CreateWallet(wallet *models.Wallet) error
-	GetUsers() ([]*models.User, error)
-	GetWalletByID(id string) (*models.Wallet, error)
-	GetWalletTransactionsByID(id string) ([]*models.Transaction, error)
-	GetTransactions() ([]*models.Transaction, error)
-	CreateTransaction(transaction *models.Transaction) error
-	GetWalletAmountDayByID(id string, week models.Week) ([]*models.Day, error)
-	GetWalletAmountWeekByID(id string, week models.Week) ([]*models.Day, error)
+	// CreateUser stores the user and sets its ID. It returns ErrUserExists when the name is taken.
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUserByName returns the user that logs in with the name, with its password hash.
+	GetUserByName(ctx context.Context, name string) (*models.User, error)
+	// GetUserByID returns the user without its password hash, or ErrUserNotFound.
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	// SetUserRole changes the role of the user with the id, or returns ErrUserNotFound.
+	SetUserRole(ctx context.Context, id, role string) error
+	// CreateRefreshToken stores the refresh token.
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// GetRefreshToken returns the refresh token with the hash, or ErrRefreshTokenNotFound.
+	GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// RotateRefreshToken marks the refresh token with the hash used and stores next, all or nothing.
+	// It returns ErrRefreshTokenReused when the token was used before.
+	RotateRefreshToken(ctx context.Context, hash string, next *models.RefreshToken) error
+	// RevokeTokenFamily revokes every refresh token of the family, and with them their access tokens.
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	// IsAccessTokenRevoked reports whether the access token with the id was issued with a revoked refresh token.
+	IsAccessTokenRevoked(ctx context.Context, id string) (bool, error)
+	CreateWallet(ctx context.Context, wallet *models.Wallet) error
+	GetUsers(ctx context.Context, page models.Pagination) ([]*models.User, *models.PageInfo, error)
+	GetWalletByID(ctx context.Context, id string) (*models.Wallet, error)
+	GetWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+		page models.Pagination) ([]*models.Transaction, *models.PageInfo, error)
+	GetTransactions(ctx context.Context, filter models.TransactionFilter,
+		page models.Pagination) ([]*models.Transaction, *models.PageInfo, error)
+	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error)
+	// GetTransactionByIdempotencyKey finds the transaction sent from the wallet under the key.
+	// Keys are chosen by clients, so they are only unique per sending wallet.
+	GetTransactionByIdempotencyKey(ctx context.Context, walletID, key string) (*models.Transaction, error)
+	GetLedgerEntriesByWalletID(ctx context.Context, id string) ([]*models.LedgerEntry, error)
+	GetWalletAmountDayByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error)
+	GetWalletAmountWeekByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error)
+	// The Stream methods call fn for each item of the matching list method, in the same order and
+	// read from the database as fn consumes them. A zero limit streams every item. They stop at the
+	// first error of fn and return it.
+	StreamUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error
+	StreamWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+		page models.Pagination, fn func(*models.Transaction) error) error
+	StreamTransactions(ctx context.Context, filter models.TransactionFilter,
+		page models.Pagination, fn func(*models.Transaction) error) error
+}
+
+// Timeouts bound the repository calls of each kind of operation. Zero means no limit
+// other than the deadline of the caller.
+type Timeouts struct {
+	// Read bounds lookups and lists.
+	Read time.Duration
+	// Write bounds creating users and wallets.
+	Write time.Duration
+	// Transaction bounds applying a transfer or a reversal, not counting the time it waits in the queue.
+	Transaction time.Duration
 }
 
 // Service holds calendar business logic and works with repository.
 type Service struct {
-	repo Repository
+	repo     Repository
+	fees     *fee.Policy
+	rates    rate.Provider
+	queue    *scheduler
+	timeouts Timeouts
+}
+
+// NewService creates the service and starts the workers of its transaction queue.
+func NewService(repo Repository, fees *fee.Policy, rates rate.Provider, queue QueueConfig, timeouts Timeouts) *Service {
+	s := &Service{repo: repo, fees: fees, rates: rates, timeouts: timeouts}
+	s.queue = newScheduler(queue, s.runTransaction)
+	s.queue.start()
+
+	return s
+}
+
+// Close stops accepting transactions and waits for the queued ones to finish.
+func (s *Service) Close() {
+	s.queue.close()
+}
+
+// withTimeout derives the context of a repository call, limited by timeout when it is set.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (s *Service) GetUsers(ctx context.Context, page models.Pagination) ([]*models.User, *models.PageInfo, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	return s.repo.GetUsers(ctx, normalizePage(page))
+}
+
+// StreamUsers calls fn for each user GetUsers would list. Unlike pages, streams are not capped and
+// a zero limit streams every user. The read timeout does not apply, a stream lasts as long as the client reads.
+func (s *Service) StreamUsers(ctx context.Context, page models.Pagination, fn func(*models.User) error) error {
+	return s.repo.StreamUsers(ctx, streamPage(page), fn)
+}
+
+// CreateWallet creates the wallet. Clients may only create wallets for themselves.
+func (s *Service) CreateWallet(ctx context.Context, wallet *models.Wallet) error {
+	err := authorizeUser(ctx, wallet.UserID)
+	if err != nil {
+		return err
+	}
+
+	if wallet.Currency == "" {
+		wallet.Currency = DefaultCurrency
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	return s.repo.CreateWallet(ctx, wallet)
+}
+
+// GetWalletByID returns the wallet when the caller owns it or is staff.
+func (s *Service) GetWalletByID(ctx context.Context, id string) (*models.Wallet, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	wallet, err := s.repo.GetWalletByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeUserOrStaff(ctx, wallet.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func (s *Service) GetWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	if !validFilter(filter) {
+		return nil, nil, ErrInvalidFilter
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	err := s.authorizeWalletOrStaff(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.repo.GetWalletTransactionsByID(ctx, id, filter, normalizePage(page))
+}
+
+// StreamWalletTransactionsByID calls fn for each transaction GetWalletTransactionsByID would list, as StreamUsers does.
+func (s *Service) StreamWalletTransactionsByID(ctx context.Context, id string, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	if !validFilter(filter) {
+		return ErrInvalidFilter
+	}
+
+	rctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	err := s.authorizeWalletOrStaff(rctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.StreamWalletTransactionsByID(ctx, id, filter, streamPage(page), fn)
+}
+
+// GetWalletLedger returns the journal postings of the wallet, oldest first, each with the running balance.
+// The balance of the last posting equals the wallet balance.
+func (s *Service) GetWalletLedger(ctx context.Context, id string) ([]*models.LedgerEntry, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	wallet, err := s.repo.GetWalletByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeUserOrStaff(ctx, wallet.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLedgerEntriesByWalletID(ctx, id)
+}
+
+// GetTransactions lists the transactions that match the filter. Clients only see the transactions of their
+// wallets, staff see them all.
+func (s *Service) GetTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination) ([]*models.Transaction, *models.PageInfo, error) {
+	// A direction is only meaningful relative to a wallet.
+	if filter.Direction != "" || !validFilter(filter) {
+		return nil, nil, ErrInvalidFilter
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	return s.repo.GetTransactions(ctx, ownTransactions(ctx, filter), normalizePage(page))
+}
+
+// StreamTransactions calls fn for each transaction GetTransactions would list, as StreamUsers does.
+func (s *Service) StreamTransactions(ctx context.Context, filter models.TransactionFilter,
+	page models.Pagination, fn func(*models.Transaction) error) error {
+	if filter.Direction != "" || !validFilter(filter) {
+		return ErrInvalidFilter
+	}
+
+	return s.repo.StreamTransactions(ctx, ownTransactions(ctx, filter), streamPage(page), fn)
+}
+
+func validFilter(filter models.TransactionFilter) bool {
+	switch {
+	case filter.Direction != "" && filter.Direction != models.DirectionIncoming &&
+		filter.Direction != models.DirectionOutgoing,
+		!filter.DateFrom.IsZero() && !filter.DateTo.IsZero() && filter.DateFrom.After(filter.DateTo),
+		filter.MinAmount < 0 || filter.MaxAmount < 0,
+		filter.MaxAmount != 0 && filter.MinAmount > filter.MaxAmount:
+		return false
+	}
+
+	return true
+}
+
+// normalizePage applies the default and maximum page size. A cursor replaces the offset.
+func normalizePage(page models.Pagination) models.Pagination {
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageLimit
+	}
+
+	if page.Limit > MaxPageLimit {
+		page.Limit = MaxPageLimit
+	}
+
+	if page.Offset < 0 || page.Cursor != "" {
+		page.Offset = 0
+	}
+
+	return page
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+// streamPage is normalizePage without the page sizes.
+func streamPage(page models.Pagination) models.Pagination {
+	if page.Limit < 0 {
+		page.Limit = 0
+	}
+
+	if page.Offset < 0 || page.Cursor != "" {
+		page.Offset = 0
+	}
+
+	return page
 }
 
-func (s *Service) CreateUser(token string) error {
-	return s.repo.CreateUser(token)
+// CreateTransaction applies the transfer and waits for the result. When the transaction carries
+// an idempotency key that was used before, the original transaction is returned instead of applying it again.
+func (s *Service) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	result, err := s.SubmitTransaction(ctx, transaction)
+	if err != nil {
+		return err
+	}
+
+	return <-result
 }
 
-func (s *Service) GetUsers() ([]*models.User, error) {
-	return s.repo.GetUsers()
+// SubmitTransaction queues the transfer by its priority and returns the channel that receives its result.
+// The transaction is updated in place before the result is sent. It is dropped when ctx is done
+// before a worker takes it. Clients may only send money from their own wallets.
+func (s *Service) SubmitTransaction(ctx context.Context, transaction *models.Transaction) (<-chan error, error) {
+	// A negative amount would move money out of the receiver, which the sender does not own.
+	if transaction.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	if transaction.CreditWalletID == transaction.DebitWalletID {
+		return nil, ErrSameWallet
+	}
+
+	rctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	err := s.authorizeWallet(rctx, transaction.CreditWalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.IdempotencyKey != "" {
+		err := s.replayTransaction(rctx, transaction)
+		if err == nil {
+			result := make(chan error, 1)
+			result <- nil
+
+			return result, nil
+		}
+
+		if !errors.Is(err, ErrTransactionNotFound) {
+			return nil, err
+		}
+	}
+
+	transaction.FeeAmount = s.fees.Calculate(transaction.Amount)
+	transaction.FeeWalletID = s.fees.WalletID()
+
+	err = s.convert(rctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queue.submit(ctx, transaction)
+}
+
+// ReverseTransaction refunds the transaction with the id, fully or partially, by a compensating
+// transaction linked to it. The refund goes through the queue like any other transaction.
+// The refund is taken from the receiver. Only staff may reverse transactions.
+func (s *Service) ReverseTransaction(ctx context.Context, id string, reversal *models.Reversal) (*models.Transaction, error) {
+	err := authorizeStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	original, err := s.repo.GetTransactionByID(rctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The refund is sent from the receiver of the original, its key is looked up there.
+	if reversal.IdempotencyKey != "" {
+		previous, err := s.repo.GetTransactionByIdempotencyKey(rctx, original.DebitWalletID, reversal.IdempotencyKey)
+		if err == nil {
+			if previous.OriginalTransactionID != id {
+				return nil, ErrIdempotencyKeyReused
+			}
+
+			return previous, nil
+		}
+
+		if !errors.Is(err, ErrTransactionNotFound) {
+			return nil, err
+		}
+	}
+
+	transaction, err := newReversal(original, reversal)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.queue.submit(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = <-result; err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
 }
 
-func (s *Service) CreateWallet(wallet *models.Wallet) error {
-	return s.repo.CreateWallet(wallet)
+// QueueDepth reports how many transactions are waiting to be processed.
+func (s *Service) QueueDepth() QueueDepth {
+	return s.queue.depth()
 }
 
-func (s *Service) GetWalletByID(id string) (*models.Wallet, error) {
-	return s.repo.GetWalletByID(id)
+// runTransaction is executed by the queue workers.
+func (s *Service) runTransaction(ctx context.Context, transaction *models.Transaction) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Transaction)
+	defer cancel()
+
+	err := s.repo.CreateTransaction(ctx, transaction)
+	if errors.Is(err, ErrDuplicateIdempotencyKey) {
+		// A concurrent request with the same key won the race, its transaction is the original.
+		return s.replayTransaction(ctx, transaction)
+	}
+
+	return err
 }
 
-func (s *Service) GetWalletTransactionsByID(id string) ([]*models.Transaction, error) {
-	return s.repo.GetWalletTransactionsByID(id)
+// convert fills in the amounts each wallet of the transaction receives in its own currency.
+func (s *Service) convert(ctx context.Context, transaction *models.Transaction) error {
+	from, err := s.walletCurrency(ctx, transaction.CreditWalletID)
+	if err != nil {
+		return err
+	}
+
+	to, err := s.walletCurrency(ctx, transaction.DebitWalletID)
+	if err != nil {
+		return err
+	}
+
+	feeCurrency, err := s.walletCurrency(ctx, transaction.FeeWalletID)
+	if err != nil {
+		return err
+	}
+
+	transaction.Rate, err = s.rates.Rate(from, to)
+	if err != nil {
+		return err
+	}
+
+	feeRate, err := s.rates.Rate(from, feeCurrency)
+	if err != nil {
+		return err
+	}
+
+	transaction.Currency = from
+	transaction.DebitCurrency = to
+	transaction.DebitAmount = rate.Convert(transaction.Amount, transaction.Rate)
+	transaction.FeeWalletAmount = rate.Convert(transaction.FeeAmount, feeRate)
+	transaction.FeeCurrency = feeCurrency
+
+	return nil
 }
 
-func (s *Service) GetTransactions() ([]*models.Transaction, error) {
-	return s.repo.GetTransactions()
+func (s *Service) walletCurrency(ctx context.Context, id string) (string, error) {
+	wallet, err := s.repo.GetWalletByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if wallet.Currency == "" {
+		return DefaultCurrency, nil
+	}
+
+	return wallet.Currency, nil
 }
 
-func (s *Service) CreateTransaction(transaction *models.Transaction) error {
-	q := deque.New()
-	if transaction.Type == 1 {
-		q.PushFront(transaction)
-	} else {
-		q.PushBack(transaction)
+// replayTransaction loads the transaction its sending wallet stored under the idempotency key into transaction.
+func (s *Service) replayTransaction(ctx context.Context, transaction *models.Transaction) error {
+	original, err := s.repo.GetTransactionByIdempotencyKey(ctx, transaction.CreditWalletID, transaction.IdempotencyKey)
+	if err != nil {
+		return err
 	}
-	for q.Len() != 0 {
-		// Consume deque and run transactions in another func
-		q.PopFront()
+
+	if original.CreditWalletID != transaction.CreditWalletID || original.DebitWalletID != transaction.DebitWalletID ||
+		original.Amount != transaction.Amount || original.Type != transaction.Type {
+		return ErrIdempotencyKeyReused
 	}
-	return s.repo.CreateTransaction(transaction)
+
+	*transaction = *original
+
+	return nil
 }
 
-func (s *Service) GetWalletAmountDayByID(id string, week models.Week) ([]*models.Day, error) {
-	return s.repo.GetWalletAmountDayByID(id, week)
+func (s *Service) GetWalletAmountDayByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	err := s.authorizeWalletOrStaff(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetWalletAmountDayByID(ctx, id, week)
 }
 
-func (s *Service) GetWalletAmountWeekByID(id string, week models.Week) ([]*models.Day, error) {
-	return s.repo.GetWalletAmountWeekByID(id, week)
+func (s *Service) GetWalletAmountWeekByID(ctx context.Context, id string, week models.Week) ([]*models.Day, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	err := s.authorizeWalletOrStaff(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetWalletAmountWeekByID(ctx, id, week)
 }
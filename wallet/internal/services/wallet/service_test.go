@@ -1,17 +1,72 @@
-package wallet
+package wallet_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/workshops/wallet/internal/config"
 	"github.com/workshops/wallet/internal/repository/models"
 	"github.com/workshops/wallet/internal/repository/postgre"
+	"github.com/workshops/wallet/internal/services/fee"
+	"github.com/workshops/wallet/internal/services/rate"
+	"github.com/workshops/wallet/internal/services/wallet"
 )
 
+const transactionColumns = "id,credit_wallet_id,debit_wallet_id,amount,currency,debit_amount,debit_currency,rate," +
+	"type,fee_amount,fee_wallet_id,fee_wallet_amount,fee_currency,credit_user_id,debit_user_id,date,idempotency_key," +
+	"original_transaction_id,refunded_amount,refunded_fee_amount,refunded_fee_wallet_amount"
+
+var transactionRows = []string{"id", "creditWalletId", "debitWalletId", "amount", "currency", "debitAmount",
+	"debitCurrency", "rate", "type", "feeAmount", "feeWalletId", "feeWalletAmount", "feeCurrency", "creditUserId", "debitUserId", "date",
+	"idempotencyKey", "originalTransactionId", "refundedAmount", "refundedFeeAmount", "refundedFeeWalletAmount"}
+
+func newFees(t *testing.T) *fee.Policy {
+	fees, err := fee.NewPolicy(&config.Fee{Percent: 1.5, WalletID: "85aa7525-4fdb-4436-a600-66ffc55e0f65"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fees
+}
+
+func newRates() rate.Provider {
+	return rate.NewStaticProvider("USD", map[string]float64{"EUR": 0.5})
+}
+
+func expectLock(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	q := "SELECT id,balance FROM wallets WHERE id IN ($1,$2,$3) ORDER BY id FOR UPDATE"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnRows(rows)
+}
+
+func expectBalance(mock sqlmock.Sqlmock, amount int, id string) {
+	q := "UPDATE wallets SET balance=balance+$1 WHERE id=$2"
+
+	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(amount, id).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectEntry(mock sqlmock.Sqlmock, account string, amount int, currency string) {
+	q := "INSERT INTO ledger_entries (transaction_id,account,amount,currency,date) VALUES ($1,$2,$3,$4,$5)"
+
+	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(sqlmock.AnyArg(), account, amount, currency, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectCount(mock sqlmock.Sqlmock, from string, total int) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM " + from)).WillReturnRows(mock.NewRows([]string{"count"}).AddRow(total))
+}
+
+func expectWallet(mock sqlmock.Sqlmock, id, currency string) {
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id).WillReturnRows(mock.NewRows([]string{"id", "balance", "userId", "currency"}).AddRow(id, 1000, "928eeecf-05ad-4e6f-ab7f-5477225b4c52", currency))
+}
+
 //nolint
 func TestGetUsers(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -21,29 +76,31 @@ func TestGetUsers(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
-
-	token := "yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw"
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
 	expectedUser := []*models.User{
 		{
-			ID:    "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
-			Token: &token,
+			ID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
+			Name: "serhii",
+			Role: models.RoleUser,
 		},
 		{
-			ID:    "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
-			Token: sql.NullString{"yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw", true},
+			ID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
+			Name: "serhii",
+			Role: models.RoleUser,
 		},
 	}
 
-	q := "SELECT * FROM users"
+	q := "SELECT id,name,role FROM users ORDER BY id LIMIT $1 OFFSET $2"
 
-	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnRows(mock.NewRows([]string{"id", "token"}).AddRow("928eeecf-05ad-4e6f-ab7f-5477225b4c52", sql.NullString{"yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw", true}).AddRow("928eeecf-05ad-4e6f-ab7f-5477225b4c52", sql.NullString{"yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw", true}))
+	expectCount(mock, "users", 2)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(wallet.DefaultPageLimit+1, 0).WillReturnRows(mock.NewRows([]string{"id", "name", "role"}).AddRow("928eeecf-05ad-4e6f-ab7f-5477225b4c52", "serhii", "user").AddRow("928eeecf-05ad-4e6f-ab7f-5477225b4c52", "serhii", "user"))
 
-	user, err := srvc.GetUsers()
+	user, info, err := srvc.GetUsers(context.Background(), models.Pagination{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedUser, user)
+	assert.Equal(t, &models.PageInfo{Total: 2}, info)
 }
 
 //nolint
@@ -55,22 +112,23 @@ func TestGetUsersError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
 	mockErr := errors.New("Error getting users")
 
-	q := "SELECT * FROM users"
+	q := "SELECT id,name,role FROM users"
 
+	expectCount(mock, "users", 2)
 	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnError(mockErr)
 
-	_, err = srvc.GetUsers()
+	_, _, err = srvc.GetUsers(context.Background(), models.Pagination{})
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
 }
 
 //nolint
-func TestCreateUsers(t *testing.T) {
+func TestRegister(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatal("Unable to connect")
@@ -78,21 +136,21 @@ func TestCreateUsers(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
-
-	token := "yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw"
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "INSERT INTO users (token) VALUES ($1)"
+	q := "INSERT INTO users (name, password_hash, role) VALUES ($1,$2,$3) RETURNING id"
 
-	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(token).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("serhii", sqlmock.AnyArg(), models.RoleUser).WillReturnRows(mock.NewRows([]string{"id"}).AddRow("928eeecf-05ad-4e6f-ab7f-5477225b4c52"))
 
-	err = srvc.CreateUser(token)
+	user, err := srvc.Register(context.Background(), models.Credentials{Name: "serhii", Password: "correct horse"})
 
 	assert.NoError(t, err)
+	assert.Equal(t, "928eeecf-05ad-4e6f-ab7f-5477225b4c52", user.ID)
+	assert.NotContains(t, user.PasswordHash, "correct horse")
 }
 
 //nolint
-func TestCreateUsersError(t *testing.T) {
+func TestRegisterError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatal("Unable to connect")
@@ -100,20 +158,18 @@ func TestCreateUsersError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
 	mockErr := errors.New("Unable to create users")
 
-	token := "yJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJOYW1lIjoic2VyaGlpIiwiZXhwIjoxNjU3MTcxMjYxfQ.p9B8ZZFmYtF6euIdDQJA9NbeCJaGCUXHxMh8wR0VyWw"
+	q := "INSERT INTO users (name, password_hash, role) VALUES ($1,$2,$3) RETURNING id"
 
-	q := "INSERT INTO users (token) VALUES ($1)"
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("serhii", sqlmock.AnyArg(), models.RoleUser).WillReturnError(mockErr)
 
-	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(token).WillReturnError(mockErr)
-
-	err = srvc.CreateUser(token)
+	_, err = srvc.Register(context.Background(), models.Credentials{Name: "serhii", Password: "correct horse"})
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
 }
 
 //nolint
@@ -125,20 +181,26 @@ func TestCreateWallet(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "INSERT INTO wallets (balance, user_id) VALUES ($1,$2)"
+	q := "INSERT INTO wallets (balance, user_id, currency) VALUES ($1,$2,$3) RETURNING id"
 
 	wallet := &models.Wallet{
 		Balance: 100,
 		UserID:  "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(wallet.Balance, wallet.UserID).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(wallet.Balance, wallet.UserID, "USD").WillReturnRows(mock.NewRows([]string{"id"}).AddRow("ce71eb21-1312-4e29-89df-039cae56007a"))
+	expectEntry(mock, "ce71eb21-1312-4e29-89df-039cae56007a", 100, "USD")
+	expectEntry(mock, "equity:USD", -100, "USD")
+	mock.ExpectCommit()
 
-	err = srvc.CreateWallet(wallet)
+	err = srvc.CreateWallet(context.Background(), wallet)
 
 	assert.NoError(t, err)
+	assert.Equal(t, "ce71eb21-1312-4e29-89df-039cae56007a", wallet.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 //nolint
@@ -150,9 +212,9 @@ func TestCreateWalletError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "INSERT INTO wallets (balance, user_id) VALUES ($1,$2)"
+	q := "INSERT INTO wallets (balance, user_id, currency) VALUES ($1,$2,$3) RETURNING id"
 
 	wallet := &models.Wallet{
 		Balance: 100,
@@ -161,12 +223,14 @@ func TestCreateWalletError(t *testing.T) {
 
 	mockErr := errors.New("Unable to create wallet")
 
-	mock.ExpectExec(regexp.QuoteMeta(q)).WithArgs(wallet.Balance, wallet.UserID).WillReturnError(mockErr)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(wallet.Balance, wallet.UserID, "USD").WillReturnError(mockErr)
+	mock.ExpectRollback()
 
-	err = srvc.CreateWallet(wallet)
+	err = srvc.CreateWallet(context.Background(), wallet)
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
 }
 
 //nolint
@@ -178,21 +242,22 @@ func TestGetWalletById(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT id,balance,user_id FROM wallets WHERE id=$1"
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
 
 	expectedWallet := &models.Wallet{
-		ID:      "096a20c7-0b2a-475a-b175-229196f23cde",
-		Balance: 100,
-		UserID:  "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+		ID:       "096a20c7-0b2a-475a-b175-229196f23cde",
+		Balance:  100,
+		UserID:   "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+		Currency: "USD",
 	}
 
 	id := "096a20c7-0b2a-475a-b175-229196f23cde"
 
-	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnRows(mock.NewRows([]string{"id", "balance", "userId"}).AddRow(expectedWallet.ID, expectedWallet.Balance, expectedWallet.UserID))
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnRows(mock.NewRows([]string{"id", "balance", "userId", "currency"}).AddRow(expectedWallet.ID, expectedWallet.Balance, expectedWallet.UserID, expectedWallet.Currency))
 
-	wallet, err := srvc.GetWalletByID(id)
+	wallet, err := srvc.GetWalletByID(context.Background(), id)
 
 	assert.NoError(t, err)
 	assert.Equal(t, wallet, expectedWallet)
@@ -207,9 +272,9 @@ func TestGetWalletByIdError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT id,balance,user_id FROM wallets WHERE id=$1"
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
 
 	mockErr := errors.New("Unable to get wallet by id")
 
@@ -217,10 +282,32 @@ func TestGetWalletByIdError(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnError(mockErr)
 
-	_, err = srvc.GetWalletByID(id)
+	_, err = srvc.GetWalletByID(context.Background(), id)
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
+}
+
+//nolint
+func TestGetWalletByIdTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{Read: 10 * time.Millisecond})
+
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WillDelayFor(time.Second).WillReturnRows(mock.NewRows([]string{"id", "balance", "userId", "currency"}))
+
+	start := time.Now()
+	_, err = srvc.GetWalletByID(context.Background(), "096a20c7-0b2a-475a-b175-229196f23cde")
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
 }
 
 //nolint
@@ -232,9 +319,9 @@ func TestGetWalletTransactionsById(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT * FROM transactions WHERE credit_wallet_id=$1 or debit_wallet_id=$1"
+	q := "SELECT " + transactionColumns + " FROM transactions WHERE (credit_wallet_id=$1 or debit_wallet_id=$1) ORDER BY date,id LIMIT $2 OFFSET $3"
 
 	id := "ce71eb21-1312-4e29-89df-039cae56007a"
 
@@ -243,32 +330,48 @@ func TestGetWalletTransactionsById(t *testing.T) {
 			ID:             "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
 			CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
 			DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
-			Amount:         20,
-			Type:           1,
-			FeeAmount:      3,
-			FeeWalletID:    "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			Amount:          20,
+			Currency:        "USD",
+			DebitAmount:     20,
+			DebitCurrency:   "USD",
+			Rate:            1,
+			Type:            1,
+			FeeAmount:       3,
+			FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			FeeWalletAmount: 3,
+			FeeCurrency:     "USD",
 			CreditUserID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
 			DebitUserID:    "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+			Date:           "2022-07-07T10:00:00Z",
 		},
 		{
 			ID:             "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
 			CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
 			DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
-			Amount:         20,
-			Type:           1,
-			FeeAmount:      3,
-			FeeWalletID:    "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			Amount:          20,
+			Currency:        "USD",
+			DebitAmount:     20,
+			DebitCurrency:   "USD",
+			Rate:            1,
+			Type:            1,
+			FeeAmount:       3,
+			FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			FeeWalletAmount: 3,
+			FeeCurrency:     "USD",
 			CreditUserID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
 			DebitUserID:    "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+			Date:           "2022-07-07T10:00:00Z",
 		},
 	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id).WillReturnRows(mock.NewRows([]string{"id", "creditWalletId", "debitWalletId", "amount", "type", "feeAmount", "feeWalletId", "creditUserId", "debitUserId"}).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a").AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a"))
+	expectCount(mock, "transactions WHERE (credit_wallet_id=$1 or debit_wallet_id=$1)", 2)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id, wallet.DefaultPageLimit+1, 0).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0))
 
-	transaction, err := srvc.GetWalletTransactionsByID(id)
+	transaction, info, err := srvc.GetWalletTransactionsByID(context.Background(), id, models.TransactionFilter{}, models.Pagination{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTransaction, transaction)
+	assert.Equal(t, &models.PageInfo{Total: 2}, info)
 }
 
 //nolint
@@ -280,20 +383,21 @@ func TestGetWalletTransactionsByIdError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT * FROM transactions WHERE credit_wallet_id=$1 or debit_wallet_id=$1"
+	q := "SELECT " + transactionColumns + " FROM transactions WHERE (credit_wallet_id=$1 or debit_wallet_id=$1)"
 
 	id := "ce71eb21-1312-4e29-89df-039cae56007a"
 
 	mockErr := errors.New("Unable to get transaction")
 
-	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id).WillReturnError(mockErr)
+	expectCount(mock, "transactions WHERE (credit_wallet_id=$1 or debit_wallet_id=$1)", 2)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnError(mockErr)
 
-	_, err = srvc.GetWalletTransactionsByID(id)
+	_, _, err = srvc.GetWalletTransactionsByID(context.Background(), id, models.TransactionFilter{}, models.Pagination{})
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
 }
 
 //nolint
@@ -305,41 +409,57 @@ func TestGetransactions(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT * FROM transactions"
+	q := "SELECT " + transactionColumns + " FROM transactions ORDER BY date,id LIMIT $1 OFFSET $2"
 
 	expectedTransaction := []*models.Transaction{
 		{
 			ID:             "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
 			CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
 			DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
-			Amount:         20,
-			Type:           1,
-			FeeAmount:      3,
-			FeeWalletID:    "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			Amount:          20,
+			Currency:        "USD",
+			DebitAmount:     20,
+			DebitCurrency:   "USD",
+			Rate:            1,
+			Type:            1,
+			FeeAmount:       3,
+			FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			FeeWalletAmount: 3,
+			FeeCurrency:     "USD",
 			CreditUserID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
 			DebitUserID:    "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+			Date:           "2022-07-07T10:00:00Z",
 		},
 		{
 			ID:             "a15abc6c-63c5-46a4-bf0c-f355a23edc2e",
 			CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
 			DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
-			Amount:         20,
-			Type:           1,
-			FeeAmount:      3,
-			FeeWalletID:    "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			Amount:          20,
+			Currency:        "USD",
+			DebitAmount:     20,
+			DebitCurrency:   "USD",
+			Rate:            1,
+			Type:            1,
+			FeeAmount:       3,
+			FeeWalletID:     "85aa7525-4fdb-4436-a600-66ffc55e0f65",
+			FeeWalletAmount: 3,
+			FeeCurrency:     "USD",
 			CreditUserID:   "928eeecf-05ad-4e6f-ab7f-5477225b4c52",
 			DebitUserID:    "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a",
+			Date:           "2022-07-07T10:00:00Z",
 		},
 	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnRows(mock.NewRows([]string{"id", "creditWalletId", "debitWalletId", "amount", "type", "feeAmount", "feeWalletId", "creditUserId", "debitUserId"}).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a").AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a"))
+	expectCount(mock, "transactions", 2)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(wallet.DefaultPageLimit+1, 0).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0))
 
-	transaction, err := srvc.GetTransactions()
+	transaction, info, err := srvc.GetTransactions(context.Background(), models.TransactionFilter{}, models.Pagination{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTransaction, transaction)
+	assert.Equal(t, &models.PageInfo{Total: 2}, info)
 }
 
 //nolint
@@ -351,16 +471,440 @@ func TestGetTransactionsError(t *testing.T) {
 	defer db.Close()
 	repo := postgre.NewRepository(db)
 
-	srvc := NewService(repo)
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
 
-	q := "SELECT * FROM transactions"
+	q := "SELECT " + transactionColumns + " FROM transactions"
 
 	mockErr := errors.New("Unable to get transaction")
 
+	expectCount(mock, "transactions", 2)
 	mock.ExpectQuery(regexp.QuoteMeta(q)).WillReturnError(mockErr)
 
-	_, err = srvc.GetTransactions()
+	_, _, err = srvc.GetTransactions(context.Background(), models.TransactionFilter{}, models.Pagination{})
 
 	assert.Error(t, err)
-	assert.Equal(t, err, mockErr)
+	assert.ErrorIs(t, err, mockErr)
+}
+
+//nolint
+func TestCreateTransactionIdempotentReplay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	q := "SELECT " + transactionColumns + " FROM transactions WHERE credit_wallet_id=$1 AND idempotency_key=$2"
+
+	key := "8d1e8e3c-6f0e-4a43-a0c4-5bd1d0d1a1a7"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("ce71eb21-1312-4e29-89df-039cae56007a", key).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", key, nil, 0, 0, 0))
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         20,
+		Type:           1,
+		IdempotencyKey: key,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a15abc6c-63c5-46a4-bf0c-f355a23edc2e", transaction.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionIdempotencyKeyReused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	q := "SELECT " + transactionColumns + " FROM transactions WHERE credit_wallet_id=$1 AND idempotency_key=$2"
+
+	key := "8d1e8e3c-6f0e-4a43-a0c4-5bd1d0d1a1a7"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("ce71eb21-1312-4e29-89df-039cae56007a", key).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", key, nil, 0, 0, 0))
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         50,
+		Type:           1,
+		IdempotencyKey: key,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.ErrorIs(t, err, wallet.ErrIdempotencyKeyReused)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionChargesFee(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	expectWallet(mock, "ce71eb21-1312-4e29-89df-039cae56007a", "USD")
+	expectWallet(mock, "096a20c7-0b2a-475a-b175-229196f23cde", "USD")
+	expectWallet(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "USD")
+
+	mock.ExpectBegin()
+	expectLock(mock, mock.NewRows([]string{"id", "balance"}).AddRow("096a20c7-0b2a-475a-b175-229196f23cde", 0).AddRow("85aa7525-4fdb-4436-a600-66ffc55e0f65", 0).AddRow("ce71eb21-1312-4e29-89df-039cae56007a", 1000))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO transactions")).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 198, "USD", 198, "USD", 1.0, 0, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0))
+	expectBalance(mock, -201, "ce71eb21-1312-4e29-89df-039cae56007a")
+	expectBalance(mock, 198, "096a20c7-0b2a-475a-b175-229196f23cde")
+	expectBalance(mock, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65")
+	expectEntry(mock, "ce71eb21-1312-4e29-89df-039cae56007a", -201, "USD")
+	expectEntry(mock, "096a20c7-0b2a-475a-b175-229196f23cde", 198, "USD")
+	expectEntry(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD")
+	mock.ExpectCommit()
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         198,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, transaction.FeeAmount)
+	assert.Equal(t, "85aa7525-4fdb-4436-a600-66ffc55e0f65", transaction.FeeWalletID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionConvertsCurrency(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	expectWallet(mock, "ce71eb21-1312-4e29-89df-039cae56007a", "USD")
+	expectWallet(mock, "096a20c7-0b2a-475a-b175-229196f23cde", "EUR")
+	expectWallet(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "EUR")
+
+	mock.ExpectBegin()
+	expectLock(mock, mock.NewRows([]string{"id", "balance"}).AddRow("096a20c7-0b2a-475a-b175-229196f23cde", 0).AddRow("85aa7525-4fdb-4436-a600-66ffc55e0f65", 0).AddRow("ce71eb21-1312-4e29-89df-039cae56007a", 1000))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO transactions")).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 200, "USD", 100, "EUR", 0.5, 0, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 2, "EUR", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0))
+	expectBalance(mock, -203, "ce71eb21-1312-4e29-89df-039cae56007a")
+	expectBalance(mock, 100, "096a20c7-0b2a-475a-b175-229196f23cde")
+	expectBalance(mock, 2, "85aa7525-4fdb-4436-a600-66ffc55e0f65")
+	expectEntry(mock, "ce71eb21-1312-4e29-89df-039cae56007a", -203, "USD")
+	expectEntry(mock, "096a20c7-0b2a-475a-b175-229196f23cde", 100, "EUR")
+	expectEntry(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 2, "EUR")
+	expectEntry(mock, "fx:USD", 200, "USD")
+	expectEntry(mock, "fx:EUR", -100, "EUR")
+	expectEntry(mock, "fx:USD", 3, "USD")
+	expectEntry(mock, "fx:EUR", -2, "EUR")
+	mock.ExpectCommit()
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         200,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 100, transaction.DebitAmount)
+	assert.Equal(t, "EUR", transaction.DebitCurrency)
+	assert.Equal(t, 0.5, transaction.Rate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionInsufficientFunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	expectWallet(mock, "ce71eb21-1312-4e29-89df-039cae56007a", "USD")
+	expectWallet(mock, "096a20c7-0b2a-475a-b175-229196f23cde", "USD")
+	expectWallet(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", "USD")
+
+	mock.ExpectBegin()
+	expectLock(mock, mock.NewRows([]string{"id", "balance"}).AddRow("096a20c7-0b2a-475a-b175-229196f23cde", 0).AddRow("85aa7525-4fdb-4436-a600-66ffc55e0f65", 0).AddRow("ce71eb21-1312-4e29-89df-039cae56007a", 200))
+	mock.ExpectRollback()
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         200,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionWalletNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	q := "SELECT id,balance,user_id,currency FROM wallets WHERE id=$1"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("ce71eb21-1312-4e29-89df-039cae56007a").WillReturnError(sql.ErrNoRows)
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "096a20c7-0b2a-475a-b175-229196f23cde",
+		Amount:         200,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestCreateTransactionSameWallet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	transaction := &models.Transaction{
+		CreditWalletID: "ce71eb21-1312-4e29-89df-039cae56007a",
+		DebitWalletID:  "ce71eb21-1312-4e29-89df-039cae56007a",
+		Amount:         200,
+	}
+
+	err = srvc.CreateTransaction(context.Background(), transaction)
+
+	assert.ErrorIs(t, err, wallet.ErrSameWallet)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestGetWalletLedger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	id := "ce71eb21-1312-4e29-89df-039cae56007a"
+
+	expectWallet(mock, id, "USD")
+
+	q := "SELECT id,transaction_id,account,amount,currency,date,SUM(amount) OVER (ORDER BY id) FROM ledger_entries WHERE account=$1 ORDER BY id"
+
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id).WillReturnRows(mock.NewRows([]string{"id", "transactionId", "account", "amount", "currency", "date", "balance"}).AddRow("1", nil, id, 1000, "USD", "2022-07-07T10:00:00Z", 1000).AddRow("2", "a15abc6c-63c5-46a4-bf0c-f355a23edc2e", id, -201, "USD", "2022-07-07T11:00:00Z", 799))
+
+	entries, err := srvc.GetWalletLedger(context.Background(), id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.LedgerEntry{
+		{ID: "1", Account: id, Amount: 1000, Currency: "USD", Date: "2022-07-07T10:00:00Z", Balance: 1000},
+		{ID: "2", TransactionID: "a15abc6c-63c5-46a4-bf0c-f355a23edc2e", Account: id, Amount: -201, Currency: "USD", Date: "2022-07-07T11:00:00Z", Balance: 799},
+	}, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestReverseTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	id := "a15abc6c-63c5-46a4-bf0c-f355a23edc2e"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + transactionColumns + " FROM transactions WHERE id=$1")).WithArgs(id).WillReturnRows(mock.NewRows(transactionRows).AddRow(id, "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 200, "USD", 200, "USD", 1.0, 0, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0))
+
+	mock.ExpectBegin()
+	expectLock(mock, mock.NewRows([]string{"id", "balance"}).AddRow("096a20c7-0b2a-475a-b175-229196f23cde", 200).AddRow("85aa7525-4fdb-4436-a600-66ffc55e0f65", 3).AddRow("ce71eb21-1312-4e29-89df-039cae56007a", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE transactions SET refunded_amount=refunded_amount+$1,refunded_fee_amount=refunded_fee_amount+$2,refunded_fee_wallet_amount=refunded_fee_wallet_amount+$3 WHERE id=$4")).WithArgs(100, 2, 2, id).WillReturnRows(mock.NewRows(transactionRows).AddRow(id, "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 200, "USD", 200, "USD", 1.0, 0, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 100, 2, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO transactions")).WillReturnRows(mock.NewRows(transactionRows).AddRow("b25abc6c-63c5-46a4-bf0c-f355a23edc2e", "096a20c7-0b2a-475a-b175-229196f23cde", "ce71eb21-1312-4e29-89df-039cae56007a", 100, "USD", 100, "USD", 1.0, 0, 2, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 2, "USD", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "2022-07-08T10:00:00Z", nil, id, 0, 0, 0))
+	expectBalance(mock, 102, "ce71eb21-1312-4e29-89df-039cae56007a")
+	expectBalance(mock, -100, "096a20c7-0b2a-475a-b175-229196f23cde")
+	expectBalance(mock, -2, "85aa7525-4fdb-4436-a600-66ffc55e0f65")
+	expectEntry(mock, "ce71eb21-1312-4e29-89df-039cae56007a", 102, "USD")
+	expectEntry(mock, "096a20c7-0b2a-475a-b175-229196f23cde", -100, "USD")
+	expectEntry(mock, "85aa7525-4fdb-4436-a600-66ffc55e0f65", -2, "USD")
+	mock.ExpectCommit()
+
+	reversal, err := srvc.ReverseTransaction(context.Background(), id, &models.Reversal{Amount: 100, RefundFee: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, id, reversal.OriginalTransactionID)
+	assert.Equal(t, "096a20c7-0b2a-475a-b175-229196f23cde", reversal.CreditWalletID)
+	assert.Equal(t, 2, reversal.FeeAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestReverseTransactionAlreadyReversed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	id := "a15abc6c-63c5-46a4-bf0c-f355a23edc2e"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + transactionColumns + " FROM transactions WHERE id=$1")).WithArgs(id).WillReturnRows(mock.NewRows(transactionRows).AddRow(id, "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 200, "USD", 200, "USD", 1.0, 0, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 200, 0, 0))
+
+	_, err = srvc.ReverseTransaction(context.Background(), id, &models.Reversal{})
+
+	assert.ErrorIs(t, err, wallet.ErrAlreadyReversed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestGetTransactionsCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	q := "SELECT " + transactionColumns + " FROM transactions ORDER BY date,id LIMIT $1 OFFSET $2"
+
+	expectCount(mock, "transactions", 3)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(2, 0).WillReturnRows(mock.NewRows(transactionRows).AddRow("a15abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-07T10:00:00Z", nil, nil, 0, 0, 0).AddRow("b25abc6c-63c5-46a4-bf0c-f355a23edc2e", "ce71eb21-1312-4e29-89df-039cae56007a", "096a20c7-0b2a-475a-b175-229196f23cde", 20, "USD", 20, "USD", 1.0, 1, 3, "85aa7525-4fdb-4436-a600-66ffc55e0f65", 3, "USD", "928eeecf-05ad-4e6f-ab7f-5477225b4c52", "92f0d2ea-f6ac-4b20-bb20-01062b29eb9a", "2022-07-08T10:00:00Z", nil, nil, 0, 0, 0))
+
+	transactions, info, err := srvc.GetTransactions(context.Background(), models.TransactionFilter{}, models.Pagination{Limit: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, 3, info.Total)
+	assert.NotEmpty(t, info.NextCursor)
+
+	q = "SELECT " + transactionColumns + " FROM transactions WHERE (date,id)>($1,$2) ORDER BY date,id LIMIT $3 OFFSET $4"
+
+	expectCount(mock, "transactions", 3)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs("2022-07-07T10:00:00Z", "a15abc6c-63c5-46a4-bf0c-f355a23edc2e", 2, 0).WillReturnRows(mock.NewRows(transactionRows))
+
+	// The cursor wins over the offset.
+	_, _, err = srvc.GetTransactions(context.Background(), models.TransactionFilter{}, models.Pagination{Offset: 10, Limit: 1, Cursor: info.NextCursor})
+
+	assert.NoError(t, err)
+
+	expectCount(mock, "transactions", 3)
+
+	_, _, err = srvc.GetTransactions(context.Background(), models.TransactionFilter{}, models.Pagination{Cursor: "not a cursor"})
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestGetWalletTransactionsFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	id := "ce71eb21-1312-4e29-89df-039cae56007a"
+	counterparty := "096a20c7-0b2a-475a-b175-229196f23cde"
+	from := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	priority := 1
+
+	filter := models.TransactionFilter{
+		DateFrom:             from,
+		MinAmount:            10,
+		Direction:            models.DirectionOutgoing,
+		CounterpartyWalletID: counterparty,
+		Type:                 &priority,
+	}
+
+	where := " WHERE credit_wallet_id=$1 AND ((credit_wallet_id=$1 and debit_wallet_id=$2) or (debit_wallet_id=$1 and credit_wallet_id=$2))" +
+		" AND date>=$3 AND amount>=$4 AND type=$5"
+	q := "SELECT " + transactionColumns + " FROM transactions" + where + " ORDER BY date,id LIMIT $6 OFFSET $7"
+
+	expectCount(mock, "transactions"+where, 0)
+	mock.ExpectQuery(regexp.QuoteMeta(q)).WithArgs(id, counterparty, from, 10, 1, wallet.DefaultPageLimit+1, 0).WillReturnRows(mock.NewRows(transactionRows))
+
+	transactions, _, err := srvc.GetWalletTransactionsByID(context.Background(), id, filter, models.Pagination{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+//nolint
+func TestGetTransactionsInvalidFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Unable to connect")
+	}
+	defer db.Close()
+	repo := postgre.NewRepository(db)
+
+	srvc := wallet.NewService(repo, newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+
+	filters := []models.TransactionFilter{
+		{Direction: models.DirectionIncoming},
+		{MinAmount: 20, MaxAmount: 10},
+		{DateFrom: time.Date(2022, 7, 2, 0, 0, 0, 0, time.UTC), DateTo: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, filter := range filters {
+		_, _, err = srvc.GetTransactions(context.Background(), filter, models.Pagination{})
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidFilter)
+	}
+
+	_, _, err = srvc.GetWalletTransactionsByID(context.Background(), "ce71eb21-1312-4e29-89df-039cae56007a", models.TransactionFilter{Direction: "sideways"}, models.Pagination{})
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidFilter)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,182 @@
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/workshops/wallet/internal/repository/models"
+)
+
+// Session is a login of a user. Every access token is issued with a refresh token that renews it.
+// A refresh token can be used once; the tokens it is exchanged for belong to the same family,
+// and reusing a spent token revokes the whole family.
+type Session struct {
+	User *models.User
+	// AccessTokenID is the jti of the access token to issue.
+	AccessTokenID string
+	RefreshToken  string
+}
+
+// StartSession opens a session for the user, who has just logged in. The refresh token expires after ttl.
+func (s *Service) StartSession(ctx context.Context, user *models.User, ttl time.Duration) (*Session, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	session, token, err := newSession(user, "", ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateRefreshToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// RefreshSession exchanges the refresh token for a new one and the id of a new access token.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken string, ttl time.Duration) (*Session, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	current, err := s.activeRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, current.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	session, next, err := newSession(user, current.FamilyID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.RotateRefreshToken(ctx, current.Hash, next)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, s.revokeReused(ctx, current)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// EndSession logs out: the family of the refresh token is revoked with every access token issued in it.
+func (s *Service) EndSession(ctx context.Context, refreshToken string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	token, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RevokeTokenFamily(ctx, token.FamilyID)
+}
+
+// IsAccessTokenRevoked reports whether the session of the access token with the jti has ended.
+func (s *Service) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	return s.repo.IsAccessTokenRevoked(ctx, id)
+}
+
+// activeRefreshToken returns the stored refresh token when it may still be exchanged.
+func (s *Service) activeRefreshToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	token, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token.Revoked, !time.Now().Before(token.ExpiresAt):
+		return nil, ErrInvalidRefreshToken
+	case token.Used:
+		return nil, s.revokeReused(ctx, token)
+	}
+
+	return token, nil
+}
+
+// revokeReused ends the family of a refresh token that was presented again. Either the client or
+// someone who stole the token already used it, and there is no telling which one asks now.
+func (s *Service) revokeReused(ctx context.Context, token *models.RefreshToken) error {
+	err := s.repo.RevokeTokenFamily(ctx, token.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// newSession creates the session and the refresh token to store for it. An empty familyID starts a new family.
+func newSession(user *models.User, familyID string, ttl time.Duration) (*Session, *models.RefreshToken, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessTokenID, err := randomToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if familyID == "" {
+		familyID, err = randomToken()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	token := &models.RefreshToken{
+		Hash:          hashToken(secret),
+		FamilyID:      familyID,
+		UserID:        user.ID,
+		AccessTokenID: accessTokenID,
+		ExpiresAt:     time.Now().Add(ttl).UTC(),
+	}
+
+	return &Session{User: user, AccessTokenID: accessTokenID, RefreshToken: secret}, token, nil
+}
+
+// randomToken returns 256 random bits, URL safe.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what is stored of a refresh token. The tokens are random, so a plain SHA-256 is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,86 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/workshops/wallet/internal/repository/memory"
+	"github.com/workshops/wallet/internal/repository/models"
+	"github.com/workshops/wallet/internal/services/wallet"
+)
+
+//nolint
+func TestRefreshSession(t *testing.T) {
+	srvc := wallet.NewService(memory.NewRepository(), newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	ctx := context.Background()
+
+	user, err := srvc.Register(ctx, models.Credentials{Name: "serhii", Password: "correct horse"})
+	require.NoError(t, err)
+
+	first, err := srvc.StartSession(ctx, user, time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.AccessTokenID)
+	assert.NotEmpty(t, first.RefreshToken)
+
+	second, err := srvc.RefreshSession(ctx, first.RefreshToken, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, second.User.ID)
+	assert.Equal(t, "serhii", second.User.Name)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.NotEqual(t, first.AccessTokenID, second.AccessTokenID)
+
+	revoked, err := srvc.IsAccessTokenRevoked(ctx, first.AccessTokenID)
+	require.NoError(t, err)
+	assert.False(t, revoked, "renewing does not revoke the previous access token")
+
+	// The first token is spent, presenting it again ends the whole session.
+	_, err = srvc.RefreshSession(ctx, first.RefreshToken, time.Hour)
+	assert.ErrorIs(t, err, wallet.ErrRefreshTokenReused)
+
+	_, err = srvc.RefreshSession(ctx, second.RefreshToken, time.Hour)
+	assert.ErrorIs(t, err, wallet.ErrInvalidRefreshToken)
+
+	for _, id := range []string{first.AccessTokenID, second.AccessTokenID} {
+		revoked, err = srvc.IsAccessTokenRevoked(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	}
+}
+
+//nolint
+func TestEndSession(t *testing.T) {
+	srvc := wallet.NewService(memory.NewRepository(), newFees(t), newRates(), wallet.QueueConfig{}, wallet.Timeouts{})
+	ctx := context.Background()
+
+	user, err := srvc.Register(ctx, models.Credentials{Name: "serhii", Password: "correct horse"})
+	require.NoError(t, err)
+
+	session, err := srvc.StartSession(ctx, user, time.Hour)
+	require.NoError(t, err)
+	other, err := srvc.StartSession(ctx, user, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, srvc.EndSession(ctx, session.RefreshToken))
+
+	revoked, err := srvc.IsAccessTokenRevoked(ctx, session.AccessTokenID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = srvc.IsAccessTokenRevoked(ctx, other.AccessTokenID)
+	require.NoError(t, err)
+	assert.False(t, revoked, "other logins of the user go on")
+
+	_, err = srvc.RefreshSession(ctx, session.RefreshToken, time.Hour)
+	assert.ErrorIs(t, err, wallet.ErrInvalidRefreshToken)
+
+	assert.ErrorIs(t, srvc.EndSession(ctx, "unknown"), wallet.ErrInvalidRefreshToken)
+
+	expired, err := srvc.StartSession(ctx, user, -time.Second)
+	require.NoError(t, err)
+
+	_, err = srvc.RefreshSession(ctx, expired.RefreshToken, time.Hour)
+	assert.ErrorIs(t, err, wallet.ErrInvalidRefreshToken)
+}
@@ -0,0 +1,3 @@
+drop table if exists transactions;
+drop table if exists wallets;
+drop table if exists users;
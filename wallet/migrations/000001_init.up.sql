@@ -65,5 +65,5 @@ create unique index if not exists transactions_id_uindex
     on transactions (id);
 
 
-INSERT INTO users (id) VALUES ('66aeb414-335a-4d1d-9dd9-6622b9c179a9');
-INSERT INTO wallets (id, balance, user_id) VALUES ('85aa7525-4fdb-4436-a600-66ffc55e0f65', 0, '66aeb414-335a-4d1d-9dd9-6622b9c179a9');
\ No newline at end of file
+INSERT INTO users (id) VALUES ('66aeb414-335a-4d1d-9dd9-6622b9c179a9') ON CONFLICT DO NOTHING;
+INSERT INTO wallets (id, balance, user_id) VALUES ('85aa7525-4fdb-4436-a600-66ffc55e0f65', 0, '66aeb414-335a-4d1d-9dd9-6622b9c179a9') ON CONFLICT DO NOTHING;
\ No newline at end of file
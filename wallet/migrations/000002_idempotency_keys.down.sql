@@ -0,0 +1,4 @@
+drop index if exists transactions_idempotency_key_uindex;
+
+alter table transactions
+    drop column if exists idempotency_key;
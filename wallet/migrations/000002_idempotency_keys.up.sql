@@ -0,0 +1,5 @@
+alter table transactions
+    add column if not exists idempotency_key text;
+
+create unique index if not exists transactions_idempotency_key_uindex
+    on transactions (idempotency_key);
@@ -0,0 +1,9 @@
+alter table transactions
+    drop column if exists currency,
+    drop column if exists debit_amount,
+    drop column if exists debit_currency,
+    drop column if exists rate,
+    drop column if exists fee_wallet_amount;
+
+alter table wallets
+    drop column if exists currency;
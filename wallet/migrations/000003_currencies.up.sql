@@ -0,0 +1,18 @@
+alter table wallets
+    add column if not exists currency char(3) not null default 'USD';
+
+alter table transactions
+    add column if not exists currency          char(3)        not null default 'USD',
+    add column if not exists debit_amount      bigint,
+    add column if not exists debit_currency    char(3)        not null default 'USD',
+    add column if not exists rate              numeric(20, 10) not null default 1,
+    add column if not exists fee_wallet_amount bigint;
+
+update transactions
+set debit_amount      = amount,
+    fee_wallet_amount = fee_amount
+where debit_amount is null;
+
+alter table transactions
+    alter column debit_amount set not null,
+    alter column fee_wallet_amount set not null;
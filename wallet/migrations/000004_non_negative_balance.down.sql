@@ -0,0 +1,2 @@
+alter table wallets
+    drop constraint if exists wallets_balance_check;
@@ -0,0 +1,9 @@
+do
+$$
+    begin
+        alter table wallets
+            add constraint wallets_balance_check check (balance >= 0);
+    exception
+        when duplicate_object then null;
+    end
+$$;
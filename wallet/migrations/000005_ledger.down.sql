@@ -0,0 +1,4 @@
+drop table if exists ledger_entries;
+
+alter table transactions
+    drop column if exists fee_currency;
@@ -0,0 +1,39 @@
+alter table transactions
+    add column if not exists fee_currency char(3);
+
+update transactions t
+set fee_currency = coalesce((select w.currency from wallets w where w.id = t.fee_wallet_id), t.currency)
+where fee_currency is null;
+
+alter table transactions
+    alter column fee_currency set not null;
+
+create table if not exists ledger_entries
+(
+    id             bigserial primary key,
+    transaction_id uuid,
+    account        text      not null,
+    amount         bigint    not null,
+    currency       char(3)   not null,
+    date           timestamp not null,
+
+    constraint ledger_entries_transactions_id_fk
+        foreign key (transaction_id) references transactions
+            on update cascade on delete cascade
+);
+
+create index if not exists ledger_entries_account_index
+    on ledger_entries (account, id);
+
+create index if not exists ledger_entries_transaction_id_index
+    on ledger_entries (transaction_id);
+
+-- Open the journal with the balances the wallets hold today, so that every wallet
+-- balance equals the sum of its postings from here on.
+insert into ledger_entries (account, amount, currency, date)
+select entry.account, entry.amount, w.currency, now()
+from wallets w
+         cross join lateral (values (w.id::text, w.balance), ('equity:' || w.currency, -w.balance))
+    as entry(account, amount)
+where w.balance <> 0
+  and not exists (select 1 from ledger_entries);
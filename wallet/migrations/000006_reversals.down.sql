@@ -0,0 +1,7 @@
+drop index if exists transactions_original_transaction_id_index;
+
+alter table transactions
+    drop constraint if exists transactions_transactions_id_fk,
+    drop column if exists original_transaction_id,
+    drop column if exists refunded_amount,
+    drop column if exists refunded_fee_amount;
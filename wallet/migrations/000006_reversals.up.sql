@@ -0,0 +1,19 @@
+alter table transactions
+    add column if not exists original_transaction_id uuid,
+    add column if not exists refunded_amount         bigint not null default 0,
+    add column if not exists refunded_fee_amount     bigint not null default 0;
+
+do
+$$
+    begin
+        alter table transactions
+            add constraint transactions_transactions_id_fk
+                foreign key (original_transaction_id) references transactions
+                    on update cascade on delete cascade;
+    exception
+        when duplicate_object then null;
+    end
+$$;
+
+create index if not exists transactions_original_transaction_id_index
+    on transactions (original_transaction_id);
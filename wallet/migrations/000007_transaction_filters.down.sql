@@ -0,0 +1,6 @@
+drop index if exists transactions_date_id_index;
+drop index if exists transactions_credit_wallet_id_date_index;
+drop index if exists transactions_debit_wallet_id_date_index;
+drop index if exists transactions_amount_index;
+drop index if exists transactions_type_index;
+drop index if exists transactions_fee_wallet_id_index;
@@ -0,0 +1,17 @@
+create index if not exists transactions_date_id_index
+    on transactions (date, id);
+
+create index if not exists transactions_credit_wallet_id_date_index
+    on transactions (credit_wallet_id, date, id);
+
+create index if not exists transactions_debit_wallet_id_date_index
+    on transactions (debit_wallet_id, date, id);
+
+create index if not exists transactions_amount_index
+    on transactions (amount);
+
+create index if not exists transactions_type_index
+    on transactions (type);
+
+create index if not exists transactions_fee_wallet_id_index
+    on transactions (fee_wallet_id);
@@ -0,0 +1,2 @@
+alter table users
+    add column if not exists name text not null default '';
@@ -0,0 +1,7 @@
+drop index if exists users_name_uindex;
+
+alter table users
+    drop column if exists password_hash;
+
+alter table users
+    add column if not exists token text;
@@ -0,0 +1,10 @@
+-- Bearer tokens are no longer stored, users log in with a password instead.
+alter table users
+    drop column if exists token;
+
+alter table users
+    add column if not exists password_hash text;
+
+-- Names are unique among the users that can log in.
+create unique index if not exists users_name_uindex
+    on users (name) where password_hash is not null;
@@ -0,0 +1,23 @@
+-- Refresh tokens are stored by their SHA-256 hash. The access token issued with each one is
+-- revoked with it, which is how logging out ends an access token before it expires.
+create table if not exists refresh_tokens
+(
+    hash            text        primary key,
+    family_id       text        not null,
+    user_id         uuid        not null,
+    access_token_id text        not null,
+    expires_at      timestamptz not null,
+    used            boolean     not null default false,
+    revoked         boolean     not null default false,
+    created_at      timestamptz not null default now(),
+
+    constraint refresh_tokens_users_id_fk
+        foreign key (user_id) references users
+            on update cascade on delete cascade
+);
+
+create index if not exists refresh_tokens_family_id_index
+    on refresh_tokens (family_id);
+
+create unique index if not exists refresh_tokens_access_token_id_uindex
+    on refresh_tokens (access_token_id);
@@ -0,0 +1,9 @@
+-- Every user has a role. Migrating to the latest version bootstraps the admin of the admin settings.
+alter table users
+    add column if not exists role text not null default 'user';
+
+alter table users
+    drop constraint if exists users_role_check;
+
+alter table users
+    add constraint users_role_check check (role in ('user', 'support', 'admin'));
@@ -0,0 +1,4 @@
+create unique index if not exists transactions_idempotency_key_uindex
+    on transactions (idempotency_key);
+
+drop index if exists transactions_credit_wallet_id_idempotency_key_uindex;
@@ -0,0 +1,2 @@
+alter table transactions
+    drop column if exists refunded_fee_wallet_amount;
@@ -0,0 +1,10 @@
+-- What the fee wallet gave back, in its own currency. Earlier refunds are estimated by the
+-- share of the fee they refunded.
+alter table transactions
+    add column if not exists refunded_fee_wallet_amount bigint not null default 0;
+
+update transactions
+set refunded_fee_wallet_amount = round(fee_wallet_amount * refunded_fee_amount::numeric / fee_amount)
+where refunded_fee_amount > 0
+  and fee_amount > 0
+  and refunded_fee_wallet_amount = 0;
@@ -0,0 +1,2 @@
+alter table transactions
+    drop constraint if exists transactions_amount_check;
@@ -0,0 +1,11 @@
+-- Transfers move a positive amount. Rows written before the check are left to be reviewed,
+-- as they are part of the ledger; validate the constraint once they are dealt with.
+do
+$$
+    begin
+        alter table transactions
+            add constraint transactions_amount_check check (amount > 0) not valid;
+    exception
+        when duplicate_object then null;
+    end
+$$;
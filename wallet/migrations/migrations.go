@@ -0,0 +1,8 @@
+// Package migrations embeds the Postgres schema. Every version has a NNNNNN_name.up.sql file
+// and may have a NNNNNN_name.down.sql file that reverts it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Postgres embed.FS